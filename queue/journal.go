@@ -0,0 +1,162 @@
+package queue
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Journal operations, every line of the journal holds one of them.
+const (
+	opEnqueue = "enqueue"
+	opRetry   = "retry"
+	opDone    = "done"
+	opDead    = "dead"
+)
+
+// journalEntry is a single line of the journal.
+type journalEntry struct {
+	Op   string
+	ID   string `json:",omitempty"`
+	Item *Item  `json:",omitempty"`
+}
+
+// journal is an append-only file of journal entries.
+type journal struct {
+	file *os.File
+}
+
+// replayJournal reads the journal at the given path and returns the pending
+// items in the order they were enqueued and the dead items. A missing journal
+// is empty, a truncated last line, e.g. after a crash, is ignored. Corrupt
+// lines before the last one are an error, their items would be lost.
+func replayJournal(path string) ([]*Item, []*Item, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("could not open journal: %v", err)
+	}
+	defer file.Close()
+
+	var order []string
+	seen := make(map[string]bool)
+	pending := make(map[string]*Item)
+	dead := make(map[string]*Item)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line, corrupt := 0, 0
+	for scanner.Scan() {
+		line++
+		if corrupt > 0 {
+			// Only the last line may be cut off, anything else lost items.
+			return nil, nil, fmt.Errorf("could not read journal: corrupt entry in line %d", corrupt)
+		}
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			corrupt = line
+			continue
+		}
+		switch entry.Op {
+		case opEnqueue, opRetry:
+			if entry.Item == nil {
+				continue
+			}
+			if !seen[entry.Item.ID] {
+				seen[entry.Item.ID] = true
+				order = append(order, entry.Item.ID)
+			}
+			pending[entry.Item.ID] = entry.Item
+			delete(dead, entry.Item.ID)
+		case opDead:
+			if entry.Item == nil {
+				continue
+			}
+			// Compacted journals contain dead items without an enqueue entry.
+			if !seen[entry.Item.ID] {
+				seen[entry.Item.ID] = true
+				order = append(order, entry.Item.ID)
+			}
+			delete(pending, entry.Item.ID)
+			dead[entry.Item.ID] = entry.Item
+		case opDone:
+			delete(pending, entry.ID)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("could not read journal: %v", err)
+	}
+
+	var pendingItems, deadItems []*Item
+	for _, id := range order {
+		if item, ok := pending[id]; ok {
+			pendingItems = append(pendingItems, item)
+			delete(pending, id)
+		}
+		if item, ok := dead[id]; ok {
+			deadItems = append(deadItems, item)
+			delete(dead, id)
+		}
+	}
+	return pendingItems, deadItems, nil
+}
+
+// openJournal rewrites the journal at the given path with just the given
+// items, dropping the history of finished ones, and opens it for appending.
+func openJournal(path string, pending, dead []*Item) (*journal, error) {
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not create journal: %v", err)
+	}
+	j := &journal{file}
+	for _, item := range pending {
+		if err := j.write(journalEntry{Op: opEnqueue, Item: item}, false); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	for _, item := range dead {
+		if err := j.write(journalEntry{Op: opDead, Item: item}, false); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("could not write journal to disk: %v", err)
+	}
+	file.Close()
+
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, fmt.Errorf("could not replace journal: %v", err)
+	}
+	file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open journal: %v", err)
+	}
+	return &journal{file}, nil
+}
+
+// write appends the given entry to the journal, if sync is set it
+// returns once the entry is on disk.
+func (j *journal) write(entry journalEntry, sync bool) error {
+	bytes, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("could not marshal journal entry: %v", err)
+	}
+	if _, err := j.file.Write(append(bytes, '\n')); err != nil {
+		return fmt.Errorf("could not write journal: %v", err)
+	}
+	if sync {
+		if err := j.file.Sync(); err != nil {
+			return fmt.Errorf("could not write journal to disk: %v", err)
+		}
+	}
+	return nil
+}
+
+func (j *journal) close() error {
+	return j.file.Close()
+}
@@ -0,0 +1,433 @@
+// Package queue implements a durable background work queue. Items are
+// journaled to an append-only file, processed by a pool of workers and
+// retried on failure; items which keep failing end up on a dead-letter list.
+// Items are processed at least once: items which were in progress when the
+// process stopped are processed again after a restart.
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Item is a unit of work in the queue.
+type Item struct {
+	ID       string
+	Kind     string
+	Payload  json.RawMessage
+	Enqueued time.Time
+	// Attempts is the number of failed attempts so far.
+	Attempts int
+	// LastError is the error of the last failed attempt.
+	LastError string `json:",omitempty"`
+	// RetryAt is the earliest time of the next attempt.
+	RetryAt time.Time
+}
+
+// Handler processes the items of a kind, it should stop when the context is cancelled.
+type Handler func(ctx context.Context, item Item) error
+
+// Logger interface describes the kind of logger we'd like to have.
+type Logger interface {
+	Infof(msgFormat string, args ...interface{})
+	Errorf(msgFormat string, args ...interface{})
+}
+
+// RetryPolicy describes how failed items are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts per item, afterwards it is dead.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, it doubles with every retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts, zero means no cap.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy of new queues.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute * 5,
+}
+
+// backoff returns the delay after the given failed attempt, starting with 1.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+// permanentError marks an error as not retryable.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks the given error as permanent, items failing
+// with it are moved to the dead-letter list right away.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// Queue struct is a durable work queue processed by a pool of workers.
+type Queue struct {
+	logger  Logger
+	workers int
+
+	mutex    sync.Mutex
+	journal  *journal
+	handlers map[string]Handler
+	retry    RetryPolicy
+	pending  []*Item
+	running  map[string]*Item
+	dead     []*Item
+	changed  chan struct{}
+	started  bool
+
+	stop      chan struct{}
+	stopOnce  sync.Once
+	ctx       context.Context
+	cancelAll context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// Open opens the queue journaled in the file at the given path, the file
+// is created if it does not exist. Pending items of earlier processes are
+// restored and processed once the queue is started with the given number
+// of workers. It accepts an optional logger, if that is nil the queue will
+// be quiet.
+func Open(path string, workers int, logger Logger) (*Queue, error) {
+	pending, dead, err := replayJournal(path)
+	if err != nil {
+		return nil, err
+	}
+	journal, err := openJournal(path, pending, dead)
+	if err != nil {
+		return nil, err
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		logger:    logger,
+		workers:   workers,
+		journal:   journal,
+		handlers:  make(map[string]Handler),
+		retry:     DefaultRetryPolicy,
+		pending:   pending,
+		running:   make(map[string]*Item),
+		dead:      dead,
+		changed:   make(chan struct{}),
+		stop:      make(chan struct{}),
+		ctx:       ctx,
+		cancelAll: cancel,
+	}, nil
+}
+
+// Handle registers the handler of the given kind of items.
+// Items of kinds without a handler wait until one is registered.
+func (q *Queue) Handle(kind string, h Handler) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.handlers[kind] = h
+	q.notify()
+}
+
+// SetRetryPolicy sets how failed items are retried.
+func (q *Queue) SetRetryPolicy(p RetryPolicy) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.retry = p
+}
+
+// Start starts the workers processing the queue.
+func (q *Queue) Start() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.started {
+		return
+	}
+	q.started = true
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+}
+
+// Enqueue adds an item of the given kind with the given payload, which is
+// marshalled to JSON. It returns the ID of the item once it is journaled.
+func (q *Queue) Enqueue(kind string, payload interface{}) (string, error) {
+	bytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal payload: %v", err)
+	}
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+	item := &Item{ID: id, Kind: kind, Payload: bytes, Enqueued: time.Now()}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if err := q.journal.write(journalEntry{Op: opEnqueue, Item: item}, true); err != nil {
+		return "", err
+	}
+	q.pending = append(q.pending, item)
+	q.notify()
+	return id, nil
+}
+
+// Len returns the number of items which are pending or in progress.
+func (q *Queue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.pending) + len(q.running)
+}
+
+// DeadLetters returns the items which failed permanently, oldest first.
+func (q *Queue) DeadLetters() []Item {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	items := make([]Item, len(q.dead))
+	for i, item := range q.dead {
+		items[i] = *item
+	}
+	return items
+}
+
+// Requeue moves the dead item with the given ID back into the queue,
+// its attempts start over.
+func (q *Queue) Requeue(id string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for i, item := range q.dead {
+		if item.ID != id {
+			continue
+		}
+		requeued := *item
+		requeued.Attempts, requeued.RetryAt = 0, time.Time{}
+		if err := q.journal.write(journalEntry{Op: opRetry, Item: &requeued}, true); err != nil {
+			return err
+		}
+		q.dead = append(q.dead[:i], q.dead[i+1:]...)
+		q.pending = append(q.pending, &requeued)
+		q.notify()
+		return nil
+	}
+	return fmt.Errorf("could not find dead item %q", id)
+}
+
+// Stop stops the workers and closes the journal. Items in progress get until
+// the given timeout to finish, afterwards they are cancelled and processed
+// again when the queue is opened the next time. The returned error lists the
+// items which had to be cancelled. Stopping a queue which was stopped
+// already has no effect.
+func (q *Queue) Stop(timeout time.Duration) error {
+	var err error
+	q.stopOnce.Do(func() {
+		err = q.shutdown(timeout)
+	})
+	return err
+}
+
+// shutdown stops the workers and closes the journal, see Stop.
+func (q *Queue) shutdown(timeout time.Duration) error {
+	close(q.stop)
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		q.mutex.Lock()
+		var ids []string
+		for id := range q.running {
+			ids = append(ids, id)
+		}
+		q.mutex.Unlock()
+		sort.Strings(ids)
+		err = fmt.Errorf("items cancelled after %s: %s", timeout, strings.Join(ids, ", "))
+		q.cancelAll()
+		<-done
+	}
+	q.cancelAll()
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if cerr := q.journal.close(); cerr != nil && err == nil {
+		err = fmt.Errorf("could not close journal: %v", cerr)
+	}
+	return err
+}
+
+// work processes items until the queue stops.
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for {
+		item, handler, ok := q.next()
+		if !ok {
+			return
+		}
+		err := q.process(item, handler)
+		q.finish(item, err)
+	}
+}
+
+// next blocks until an item is due and returns it along with its handler.
+// It returns false once the queue stops.
+func (q *Queue) next() (*Item, Handler, bool) {
+	for {
+		q.mutex.Lock()
+		now := time.Now()
+		var wakeup time.Time
+		for i, item := range q.pending {
+			handler, ok := q.handlers[item.Kind]
+			if !ok {
+				continue
+			}
+			if item.RetryAt.After(now) {
+				if wakeup.IsZero() || item.RetryAt.Before(wakeup) {
+					wakeup = item.RetryAt
+				}
+				continue
+			}
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			q.running[item.ID] = item
+			q.mutex.Unlock()
+			return item, handler, true
+		}
+		changed := q.changed
+		q.mutex.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !wakeup.IsZero() {
+			timer = time.NewTimer(wakeup.Sub(now))
+			timeout = timer.C
+		}
+		select {
+		case <-q.stop:
+		case <-changed:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		select {
+		case <-q.stop:
+			return nil, nil, false
+		default:
+		}
+	}
+}
+
+// process runs the handler of the given item, recovering from panics.
+func (q *Queue) process(item *Item, handler Handler) (err error) {
+	defer func() {
+		if recover := recover(); recover != nil {
+			q.error("ITEM=%s Panic: %+v", item.ID, recover)
+			q.error("%s", debug.Stack())
+			err = fmt.Errorf("handler panicked: %v", recover)
+		}
+	}()
+	return handler(q.ctx, *item)
+}
+
+// finish journals the outcome of the given attempt.
+func (q *Queue) finish(item *Item, err error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	delete(q.running, item.ID)
+
+	// Items cancelled by Stop are processed again after a restart.
+	if err != nil && q.ctx.Err() != nil {
+		q.info("ITEM=%s Cancelled, item stays queued.", item.ID)
+		return
+	}
+	if err == nil {
+		if jerr := q.journal.write(journalEntry{Op: opDone, ID: item.ID}, true); jerr != nil {
+			q.error("ITEM=%s Could not journal processed item: %v.", item.ID, jerr)
+		}
+		q.info("ITEM=%s Processed %s item.", item.ID, item.Kind)
+		return
+	}
+
+	failed := *item
+	failed.Attempts++
+	failed.LastError = err.Error()
+	var pe *permanentError
+	if failed.Attempts >= q.retry.MaxAttempts || errors.As(err, &pe) {
+		if jerr := q.journal.write(journalEntry{Op: opDead, Item: &failed}, true); jerr != nil {
+			q.error("ITEM=%s Could not journal dead item: %v.", item.ID, jerr)
+		}
+		q.dead = append(q.dead, &failed)
+		q.error("ITEM=%s Could not process %s item, giving up after %d attempts: %v.", item.ID, item.Kind, failed.Attempts, err)
+		return
+	}
+
+	backoff := q.retry.backoff(failed.Attempts)
+	failed.RetryAt = time.Now().Add(backoff)
+	if jerr := q.journal.write(journalEntry{Op: opRetry, Item: &failed}, true); jerr != nil {
+		q.error("ITEM=%s Could not journal failed item: %v.", item.ID, jerr)
+	}
+	q.pending = append(q.pending, &failed)
+	q.notify()
+	q.error("ITEM=%s Could not process %s item: %v, retrying in %s.", item.ID, item.Kind, err, backoff)
+}
+
+// notify wakes up all waiting workers, the mutex must be held.
+func (q *Queue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// newID returns a random item ID.
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("could not generate item id: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (q *Queue) info(msgFormat string, args ...interface{}) {
+	if q.logger != nil {
+		q.logger.Infof(msgFormat, args...)
+	}
+}
+
+func (q *Queue) error(msgFormat string, args ...interface{}) {
+	if q.logger != nil {
+		q.logger.Errorf(msgFormat, args...)
+	}
+}
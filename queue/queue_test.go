@@ -0,0 +1,178 @@
+package queue_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/queue"
+)
+
+// waitFor polls the given condition until it is met.
+func waitFor(t *testing.T, condition func() bool) {
+	for i := 0; i < 1000; i++ {
+		if condition() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("Condition not met in time")
+}
+
+type recorder struct {
+	mutex    sync.Mutex
+	payloads []string
+	failures int
+}
+
+func (r *recorder) handle(ctx context.Context, item queue.Item) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("temporary failure")
+	}
+	var payload string
+	if err := json.Unmarshal(item.Payload, &payload); err != nil {
+		return queue.Permanent(err)
+	}
+	r.payloads = append(r.payloads, payload)
+	return nil
+}
+
+func (r *recorder) getPayloads() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string(nil), r.payloads...)
+}
+
+func TestQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "queue-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	q, err := queue.Open(filepath.Join(dir, "journal"), 2, nil)
+	assert.NoError(t, err)
+	q.SetRetryPolicy(queue.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	r := &recorder{failures: 2}
+	q.Handle("email", r.handle)
+	q.Start()
+
+	// Failures are retried, undecodable items end up as dead letters.
+	_, err = q.Enqueue("email", "alice@example.com")
+	assert.NoError(t, err)
+	poison, err := q.Enqueue("email", 42)
+	assert.NoError(t, err)
+	waitFor(t, func() bool { return q.Len() == 0 })
+
+	assert.Equals(t, []string{"alice@example.com"}, r.getPayloads())
+	dead := q.DeadLetters()
+	assert.Equals(t, 1, len(dead))
+	assert.Equals(t, poison, dead[0].ID)
+	assert.NoError(t, q.Stop(time.Second))
+}
+
+func TestQueue_Restart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "queue-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "journal")
+
+	// Items enqueued before stopping are processed after a restart.
+	q, err := queue.Open(path, 1, nil)
+	assert.NoError(t, err)
+	_, err = q.Enqueue("thumbnail", "a.png")
+	assert.NoError(t, err)
+	_, err = q.Enqueue("thumbnail", "b.png")
+	assert.NoError(t, err)
+	assert.NoError(t, q.Stop(time.Second))
+
+	q, err = queue.Open(path, 1, nil)
+	assert.NoError(t, err)
+	assert.Equals(t, 2, q.Len())
+	r := &recorder{}
+	q.Handle("thumbnail", r.handle)
+	q.Start()
+	waitFor(t, func() bool { return q.Len() == 0 })
+	assert.Equals(t, []string{"a.png", "b.png"}, r.getPayloads())
+	assert.NoError(t, q.Stop(time.Second))
+
+	// Processed items are gone for good.
+	q, err = queue.Open(path, 1, nil)
+	assert.NoError(t, err)
+	assert.Equals(t, 0, q.Len())
+	assert.NoError(t, q.Stop(time.Second))
+}
+
+func TestQueue_Requeue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "queue-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "journal")
+
+	q, err := queue.Open(path, 1, nil)
+	assert.NoError(t, err)
+	q.SetRetryPolicy(queue.RetryPolicy{MaxAttempts: 1})
+	r := &recorder{failures: 1}
+	q.Handle("email", r.handle)
+	q.Start()
+	id, err := q.Enqueue("email", "bob@example.com")
+	assert.NoError(t, err)
+	waitFor(t, func() bool { return len(q.DeadLetters()) == 1 })
+	assert.NoError(t, q.Stop(time.Second))
+
+	// Dead letters survive restarts, also once the journal was compacted,
+	// and can be requeued.
+	for i := 0; i < 2; i++ {
+		q, err = queue.Open(path, 1, nil)
+		assert.NoError(t, err)
+		assert.Equals(t, 1, len(q.DeadLetters()))
+		assert.NoError(t, q.Stop(time.Second))
+	}
+	q, err = queue.Open(path, 1, nil)
+	assert.NoError(t, err)
+	q.Handle("email", r.handle)
+	q.Start()
+	assert.NoError(t, q.Requeue(id))
+	waitFor(t, func() bool { return len(r.getPayloads()) == 1 })
+	assert.Equals(t, 0, len(q.DeadLetters()))
+	assert.Error(t, q.Requeue(id))
+	assert.NoError(t, q.Stop(time.Second))
+
+	// Stopping twice is fine.
+	assert.NoError(t, q.Stop(time.Second))
+}
+
+func TestQueue_CorruptJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "queue-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "journal")
+
+	q, err := queue.Open(path, 1, nil)
+	assert.NoError(t, err)
+	_, err = q.Enqueue("email", "alice@example.com")
+	assert.NoError(t, err)
+	assert.NoError(t, q.Stop(time.Second))
+	valid, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+
+	// A truncated last line, e.g. after a crash, is ignored.
+	assert.NoError(t, ioutil.WriteFile(path, append(valid, `{"Op":"enq`...), 0644))
+	q, err = queue.Open(path, 1, nil)
+	assert.NoError(t, err)
+	assert.Equals(t, 1, q.Len())
+	assert.NoError(t, q.Stop(time.Second))
+
+	// Corrupt entries before the last line are reported.
+	assert.NoError(t, ioutil.WriteFile(path, append([]byte("{\"Op\":\"enq\n"), valid...), 0644))
+	_, err = queue.Open(path, 1, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,284 @@
+package scheduler
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Window interface describes recurring periods of time, e.g. business hours.
+type Window interface {
+	// Contains returns whether t lies within a period of the window.
+	Contains(t time.Time) bool
+	// End returns the end of the period containing t.
+	End(t time.Time) time.Time
+}
+
+// BlackoutPolicy decides what happens to runs which fall into a blackout.
+type BlackoutPolicy int
+
+// All available blackout policies.
+const (
+	// SkipBlackout skips runs within the blackout, the job continues
+	// with its first slot after the blackout.
+	SkipBlackout BlackoutPolicy = iota
+	// DeferBlackout defers runs within the blackout to its end.
+	DeferBlackout
+)
+
+// Blackout describes when jobs must not run. Blackouts only affect scheduled
+// and dependency triggered runs, manual runs are always executed.
+type Blackout struct {
+	Policy  BlackoutPolicy
+	Windows []Window
+}
+
+// maxBlackoutSteps limits the search for the end of a blackout
+// and for the first slot after it.
+const maxBlackoutSteps = 10000
+
+// contains returns whether t lies within the blackout.
+func (b *Blackout) contains(t time.Time) bool {
+	if b == nil {
+		return false
+	}
+	for _, w := range b.Windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// blackoutEnd returns the first time at or after t which lies within none
+// of the given blackouts. It is zero if there is no such time.
+func blackoutEnd(t time.Time, blackouts ...*Blackout) time.Time {
+	for i := 0; i < maxBlackoutSteps; i++ {
+		in := false
+		for _, b := range blackouts {
+			if b == nil {
+				continue
+			}
+			for _, w := range b.Windows {
+				if w.Contains(t) {
+					t, in = w.End(t), true
+				}
+			}
+		}
+		if !in {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// SetBlackout sets when the job must not run, nil removes the blackout.
+// The job also respects the blackout of its service, see Service.SetBlackout.
+func (j *Job) SetBlackout(b *Blackout) {
+	j.mutex.Lock()
+	j.blackout = b
+	j.mutex.Unlock()
+	j.reschedule()
+}
+
+// Blackout returns when the job must not run.
+func (j *Job) Blackout() *Blackout {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.blackout
+}
+
+// setServiceBlackout sets the blackout of the job's service.
+func (j *Job) setServiceBlackout(b *Blackout) {
+	j.mutex.Lock()
+	j.serviceBlackout = b
+	j.mutex.Unlock()
+	j.reschedule()
+}
+
+// blackedOut returns whether t lies within the job's or the service's blackout
+// and if so, how to handle it. Skipping wins if both contain t. The mutex must be held.
+func (j *Job) blackedOut(t time.Time) (BlackoutPolicy, bool) {
+	policy, in := DeferBlackout, false
+	for _, b := range []*Blackout{j.blackout, j.serviceBlackout} {
+		if b.contains(t) {
+			in = true
+			if b.Policy == SkipBlackout {
+				policy = SkipBlackout
+			}
+		}
+	}
+	return policy, in
+}
+
+// deferRun defers the given trigger to the end of the current blackout. A
+// trigger which is deferred already absorbs the new one. It returns false
+// if the blackout doesn't end. The mutex must be held.
+func (j *Job) deferRun(trigger Trigger) bool {
+	if j.deferring {
+		j.info("JOB=%s Job is blacked out, a run is deferred already.", j.Name())
+		return true
+	}
+	now := j.now()
+	end := blackoutEnd(now, j.blackout, j.serviceBlackout)
+	if end.IsZero() {
+		return false
+	}
+	j.deferring = true
+	j.deferredTrigger = trigger
+	j.resetRunTimer(j.deferTimer, end)
+	j.info("JOB=%s Job is blacked out, deferring run to %s.", j.Name(), end.Format(time.RFC3339))
+	return true
+}
+
+// SetBlackout sets when the jobs of the service must not run, in addition to
+// their own blackouts. It applies to all current and future jobs, nil removes it.
+func (s *Service) SetBlackout(b *Blackout) {
+	s.mutex.Lock()
+	s.blackout = b
+	jobs := append([]*Job(nil), s.jobs...)
+	s.mutex.Unlock()
+
+	for _, j := range jobs {
+		j.setServiceBlackout(b)
+	}
+}
+
+// dailyWindow spans the same time of every day.
+type dailyWindow struct {
+	from, to time.Duration
+}
+
+// DailyWindow returns a window spanning from the given time of day until the
+// given one, both as offsets since midnight. If from is after to, the window
+// spans midnight, e.g. from 22:00 until 06:00.
+func DailyWindow(from, to time.Duration) Window {
+	return &dailyWindow{from: from, to: to}
+}
+
+// ParseDailyWindow parses a window like "09:00-17:00", see DailyWindow.
+func ParseDailyWindow(s string) (Window, error) {
+	bounds := strings.SplitN(s, "-", 2)
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("could not parse daily window %q: expected from-to", s)
+	}
+	var offsets [2]time.Duration
+	for i, bound := range bounds {
+		t, err := time.Parse("15:04", strings.TrimSpace(bound))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse daily window %q: %v", s, err)
+		}
+		offsets[i] = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	return DailyWindow(offsets[0], offsets[1]), nil
+}
+
+// Contains returns whether the time of day of t lies within the window.
+func (w *dailyWindow) Contains(t time.Time) bool {
+	offset := timeOfDay(t)
+	if w.from <= w.to {
+		return offset >= w.from && offset < w.to
+	}
+	return offset >= w.from || offset < w.to
+}
+
+// End returns the end of the window containing t.
+func (w *dailyWindow) End(t time.Time) time.Time {
+	if w.from > w.to && timeOfDay(t) >= w.from {
+		return onDay(t, 1, w.to)
+	}
+	return onDay(t, 0, w.to)
+}
+
+// weekdaysWindow spans whole weekdays.
+type weekdaysWindow struct {
+	days map[time.Weekday]bool
+}
+
+// Weekdays returns a window spanning the given days of the week, e.g. the weekend.
+func Weekdays(days ...time.Weekday) Window {
+	w := &weekdaysWindow{days: make(map[time.Weekday]bool)}
+	for _, d := range days {
+		w.days[d] = true
+	}
+	return w
+}
+
+// Contains returns whether t lies on one of the weekdays.
+func (w *weekdaysWindow) Contains(t time.Time) bool {
+	return w.days[t.Weekday()]
+}
+
+// End returns the end of the day of t.
+func (w *weekdaysWindow) End(t time.Time) time.Time {
+	return onDay(t, 1, 0)
+}
+
+// datesWindow spans whole calendar days.
+type datesWindow struct {
+	dates map[string]bool
+}
+
+const dateLayout = "2006-01-02"
+
+// Dates returns a window spanning the calendar days of the given times,
+// e.g. public holidays. The days are compared in the location of the job.
+func Dates(dates ...time.Time) Window {
+	w := &datesWindow{dates: make(map[string]bool)}
+	for _, d := range dates {
+		w.dates[d.Format(dateLayout)] = true
+	}
+	return w
+}
+
+// LoadDates loads a window spanning calendar days from the given file,
+// which contains one date like 2019-12-24 per line. Empty lines
+// and lines starting with # are ignored, see Dates.
+func LoadDates(path string) (Window, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open dates file: %v", err)
+	}
+	defer file.Close()
+
+	var dates []time.Time
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		date, err := time.Parse(dateLayout, line)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse dates file: %v", err)
+		}
+		dates = append(dates, date)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read dates file: %v", err)
+	}
+	return Dates(dates...), nil
+}
+
+// Contains returns whether t lies on one of the dates.
+func (w *datesWindow) Contains(t time.Time) bool {
+	return w.dates[t.Format(dateLayout)]
+}
+
+// End returns the end of the day of t.
+func (w *datesWindow) End(t time.Time) time.Time {
+	return onDay(t, 1, 0)
+}
+
+// timeOfDay returns the wall clock time of t as offset since midnight.
+func timeOfDay(t time.Time) time.Duration {
+	h, m, s := t.Clock()
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second + time.Duration(t.Nanosecond())
+}
+
+// onDay returns the given wall clock time on the day of t plus the given number of days.
+func onDay(t time.Time, days int, offset time.Duration) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+days, 0, 0, 0, int(offset), t.Location())
+}
@@ -0,0 +1,112 @@
+package scheduler_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+func TestDailyWindow(t *testing.T) {
+	w, err := scheduler.ParseDailyWindow("22:00-06:00")
+	assert.NoError(t, err)
+
+	day := time.Date(2019, time.March, 15, 0, 0, 0, 0, time.UTC)
+	assert.True(t, w.Contains(day.Add(time.Hour*23)), "23:00 should be within the window")
+	assert.True(t, w.Contains(day.Add(time.Hour*5)), "05:00 should be within the window")
+	assert.True(t, !w.Contains(day.Add(time.Hour*6)), "06:00 should not be within the window")
+	assert.Equals(t, day.Add(time.Hour*30), w.End(day.Add(time.Hour*23)))
+
+	_, err = scheduler.ParseDailyWindow("09:00")
+	assert.Error(t, err)
+}
+
+func TestLoadDates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scheduler-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "holidays")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("# Holidays\n2019-12-24\n\n2019-12-25\n"), 0644))
+	w, err := scheduler.LoadDates(path)
+	assert.NoError(t, err)
+	assert.True(t, w.Contains(time.Date(2019, time.December, 25, 12, 0, 0, 0, time.UTC)), "christmas should be a holiday")
+	assert.True(t, !w.Contains(time.Date(2019, time.December, 26, 12, 0, 0, 0, time.UTC)), "boxing day should not be a holiday")
+}
+
+func TestBlackout_Defer(t *testing.T) {
+	start := time.Date(2019, time.March, 15, 8, 30, 0, 0, time.UTC) // Friday.
+	clock := scheduler.NewFakeClock(start)
+	task := &testTask{}
+	job := scheduler.New(task,
+		scheduler.WithInterval(time.Hour),
+		scheduler.WithClock(clock),
+		scheduler.WithBlackout(&scheduler.Blackout{
+			Policy:  scheduler.DeferBlackout,
+			Windows: []scheduler.Window{scheduler.DailyWindow(time.Hour*9, time.Hour*17)},
+		}),
+	)
+	defer job.Stop()
+	clock.BlockUntil(1)
+
+	// The 09:30 run is deferred until the end of business hours.
+	assert.Equals(t, time.Date(2019, time.March, 15, 17, 0, 0, 0, time.UTC), job.NextRun())
+
+	// Manual runs are not affected.
+	job.RunNow()
+	waitFor(t, func() bool { return task.getCount() == 1 })
+
+	advance(clock, 1, job.NextRun().Sub(start))
+	waitFor(t, func() bool { return task.getCount() == 2 })
+	assert.Equals(t, time.Date(2019, time.March, 15, 18, 0, 0, 0, time.UTC), job.NextRun())
+}
+
+func TestBlackout_DeferDependency(t *testing.T) {
+	clock := scheduler.NewFakeClock(time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC)) // Friday.
+	fetch, index := &testTask{}, &testTask{}
+	fetchJob := scheduler.New(fetch)
+	indexJob := scheduler.New(index,
+		scheduler.WithClock(clock),
+		scheduler.WithBlackout(&scheduler.Blackout{
+			Policy:  scheduler.DeferBlackout,
+			Windows: []scheduler.Window{scheduler.DailyWindow(time.Hour*9, time.Hour*17)},
+		}),
+	)
+	s := scheduler.NewService()
+	s.AddJob(fetchJob)
+	s.AddJob(indexJob)
+	defer s.StopAll(time.Second)
+	assert.NoError(t, s.AddDependency(fetchJob, indexJob))
+
+	// Dependency triggers within the blackout run once it is over.
+	fetchJob.RunNow()
+	clock.BlockUntil(1)
+	assert.Equals(t, 0, index.getCount())
+	clock.Advance(7 * time.Hour)
+	waitFor(t, func() bool { return index.getCount() == 1 })
+	last, ok := indexJob.LastRecord()
+	assert.True(t, ok, "downstream job should have run")
+	assert.Equals(t, scheduler.DependencyTrigger, last.Trigger)
+}
+
+func TestBlackout_Skip(t *testing.T) {
+	clock := scheduler.NewFakeClock(time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC)) // Friday.
+	job := scheduler.New(&testTask{}, scheduler.WithSchedule(scheduler.MustParseCron("@daily")), scheduler.WithClock(clock))
+	defer job.Stop()
+	clock.BlockUntil(1)
+
+	s := scheduler.NewService()
+	s.AddJob(job)
+	s.SetBlackout(&scheduler.Blackout{
+		Policy:  scheduler.SkipBlackout,
+		Windows: []scheduler.Window{scheduler.Weekdays(time.Saturday, time.Sunday)},
+	})
+
+	// The runs on the weekend are skipped.
+	monday := time.Date(2019, time.March, 18, 0, 0, 0, 0, time.UTC)
+	waitFor(t, func() bool { return job.NextRun() == monday })
+}
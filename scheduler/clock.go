@@ -0,0 +1,212 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock interface describes the source of time used by jobs.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer interface describes a timer as created by a Clock, see time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker interface describes a ticker as created by a Clock, see time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// realClock implements a Clock using the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// FakeClock implements a Clock which only moves when advanced manually.
+// Timers and tickers fire synchronously from within Advance.
+type FakeClock struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFakeClock constructs a new fake clock set to the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mutex)
+	return c
+}
+
+// Now returns the current time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// NewTimer creates a timer which fires once the clock advanced by d.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	return c.newTimer(d, 0)
+}
+
+// NewTicker creates a ticker which fires every time the clock advanced by d.
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	return fakeTicker{c.newTimer(d, d)}
+}
+
+func (c *FakeClock) newTimer(d, period time.Duration) *fakeTimer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t := &fakeTimer{
+		clock:  c,
+		c:      make(chan time.Time, 1),
+		at:     c.now.Add(d),
+		period: period,
+		armed:  true,
+	}
+	c.timers = append(c.timers, t)
+	t.fireExpired()
+	c.cond.Broadcast()
+	return t
+}
+
+// Advance moves the clock forward by d, firing all timers which expire
+// on the way in chronological order. Like real tickers, fake tickers drop
+// ticks if the previous one was not received yet.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	target := c.now.Add(d)
+	for {
+		var next *fakeTimer
+		for _, t := range c.timers {
+			if t.armed && !t.at.After(target) && (next == nil || t.at.Before(next.at)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		c.now = next.at
+		select {
+		case next.c <- c.now:
+		default:
+		}
+		if next.period > 0 {
+			next.at = next.at.Add(next.period)
+		} else {
+			next.armed = false
+		}
+	}
+	c.now = target
+	c.cond.Broadcast()
+}
+
+// BlockUntil blocks until at least n timers or tickers are waiting to fire.
+// This allows to wait for a job to arm its timer before advancing the clock.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for c.armed() < n {
+		c.cond.Wait()
+	}
+}
+
+// armed returns the number of armed timers, the mutex must be held.
+func (c *FakeClock) armed() int {
+	n := 0
+	for _, t := range c.timers {
+		if t.armed {
+			n++
+		}
+	}
+	return n
+}
+
+type fakeTimer struct {
+	clock  *FakeClock
+	c      chan time.Time
+	at     time.Time
+	period time.Duration
+	armed  bool
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	armed := t.armed
+	t.armed = false
+	return armed
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	armed := t.armed
+	t.at = t.clock.now.Add(d)
+	t.armed = true
+	t.fireExpired()
+	t.clock.cond.Broadcast()
+	return armed
+}
+
+// fireExpired fires the timer right away if it is already due, like a real
+// timer created with a non-positive duration. The clock's mutex must be held.
+func (t *fakeTimer) fireExpired() {
+	if t.period > 0 || t.at.After(t.clock.now) {
+		return
+	}
+	select {
+	case t.c <- t.clock.now:
+	default:
+	}
+	t.armed = false
+}
+
+type fakeTicker struct {
+	*fakeTimer
+}
+
+func (t fakeTicker) Stop() {
+	t.fakeTimer.Stop()
+}
@@ -0,0 +1,193 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PipelineStage describes a job which is part of a dependency graph.
+type PipelineStage struct {
+	JobSummary
+
+	// Names of the jobs this job depends on and the ones depending on it.
+	Upstream   []string
+	Downstream []string
+
+	// Names of the upstream jobs which did not succeed yet since
+	// this job was last triggered by its dependencies.
+	Waiting []string
+}
+
+// AddDependency declares that downstream depends on upstream. Once all
+// upstream jobs of a job succeeded, the job is triggered. Both jobs must be
+// part of the service and the dependency must not introduce a cycle.
+func (s *Service) AddDependency(upstream, downstream *Job) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.contains(upstream) || !s.contains(downstream) {
+		return errors.New("jobs must be added to the service before declaring dependencies")
+	}
+	if upstream == downstream {
+		return fmt.Errorf("job %s can not depend on itself", upstream.Name())
+	}
+	for _, u := range s.upstreams[downstream] {
+		if u == upstream {
+			return nil
+		}
+	}
+	if s.reaches(downstream, upstream) {
+		return fmt.Errorf("dependency %s -> %s introduces a cycle", upstream.Name(), downstream.Name())
+	}
+
+	if s.upstreams == nil {
+		s.upstreams = make(map[*Job][]*Job)
+		s.satisfied = make(map[*Job]map[*Job]bool)
+		s.hooked = make(map[*Job]bool)
+	}
+	if !s.hooked[upstream] {
+		upstream.AddListener(s.upstreamFinished)
+		s.hooked[upstream] = true
+	}
+	s.upstreams[downstream] = append(s.upstreams[downstream], upstream)
+	if s.satisfied[downstream] == nil {
+		s.satisfied[downstream] = make(map[*Job]bool)
+	}
+	return nil
+}
+
+// Pipeline returns all jobs which are part of a dependency
+// graph, upstream jobs are listed before their downstream jobs.
+func (s *Service) Pipeline() []PipelineStage {
+	s.mutex.Lock()
+	jobs := append([]*Job(nil), s.jobs...)
+	upstreams := make(map[*Job][]*Job)
+	downstreams := make(map[*Job][]*Job)
+	satisfied := make(map[*Job]map[*Job]bool)
+	for _, j := range jobs {
+		for _, u := range s.upstreams[j] {
+			upstreams[j] = append(upstreams[j], u)
+			downstreams[u] = append(downstreams[u], j)
+		}
+		satisfied[j] = make(map[*Job]bool)
+		for u, ok := range s.satisfied[j] {
+			satisfied[j][u] = ok
+		}
+	}
+	s.mutex.Unlock()
+
+	// Kahn's algorithm, preserving the order in which jobs were added.
+	indegree := make(map[*Job]int)
+	for _, j := range jobs {
+		indegree[j] = len(upstreams[j])
+	}
+	var stages []PipelineStage
+	done := make(map[*Job]bool)
+	for len(done) < len(jobs) {
+		for _, j := range jobs {
+			if done[j] || indegree[j] > 0 {
+				continue
+			}
+			done[j] = true
+			for _, d := range downstreams[j] {
+				indegree[d]--
+			}
+			if len(upstreams[j]) == 0 && len(downstreams[j]) == 0 {
+				continue
+			}
+
+			stage := PipelineStage{JobSummary: j.Summary()}
+			for _, u := range upstreams[j] {
+				stage.Upstream = append(stage.Upstream, u.Name())
+				if !satisfied[j][u] {
+					stage.Waiting = append(stage.Waiting, u.Name())
+				}
+			}
+			for _, d := range downstreams[j] {
+				stage.Downstream = append(stage.Downstream, d.Name())
+			}
+			stages = append(stages, stage)
+		}
+	}
+	return stages
+}
+
+// upstreamFinished listens to the events of a job others depend on.
+func (s *Service) upstreamFinished(e Event) {
+	if e.Type != EventSucceeded {
+		return
+	}
+	upstream := e.Job
+
+	var ready []*Job
+	s.mutex.Lock()
+	for downstream, upstreams := range s.upstreams {
+		if !containsJob(upstreams, upstream) {
+			continue
+		}
+		satisfied := s.satisfied[downstream]
+		satisfied[upstream] = true
+		if len(satisfied) == len(upstreams) {
+			s.satisfied[downstream] = make(map[*Job]bool)
+			ready = append(ready, downstream)
+		}
+	}
+	s.mutex.Unlock()
+
+	for _, j := range ready {
+		j.info("JOB=%s All upstream jobs succeeded.", j.Name())
+		j.dependencyTrigger()
+	}
+}
+
+// dependencyTrigger triggers the job without blocking the upstream's
+// listener. A trigger still waiting for the job absorbs the new one.
+func (j *Job) dependencyTrigger() {
+	select {
+	case j.runDependency <- struct{}{}:
+	default:
+	}
+}
+
+// removeDependencies removes all edges of the given job, the mutex must be held.
+func (s *Service) removeDependencies(j *Job) {
+	delete(s.upstreams, j)
+	delete(s.satisfied, j)
+	for downstream, upstreams := range s.upstreams {
+		for i, u := range upstreams {
+			if u == j {
+				s.upstreams[downstream] = append(upstreams[:i:i], upstreams[i+1:]...)
+				delete(s.satisfied[downstream], j)
+				break
+			}
+		}
+	}
+}
+
+// reaches returns whether to can be reached from from by following
+// downstream edges, the mutex must be held.
+func (s *Service) reaches(from, to *Job) bool {
+	if from == to {
+		return true
+	}
+	for downstream, upstreams := range s.upstreams {
+		if containsJob(upstreams, from) && s.reaches(downstream, to) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains returns whether the job is part of the service, the mutex must be held.
+func (s *Service) contains(j *Job) bool {
+	return containsJob(s.jobs, j)
+}
+
+func containsJob(jobs []*Job, j *Job) bool {
+	for _, job := range jobs {
+		if job == j {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+func TestDependencies(t *testing.T) {
+	fetch, index := &testTask{}, &failingTask{}
+	s := scheduler.NewService()
+	fetchJob := scheduler.NewJob(fetch, nil, time.Hour)
+	indexJob := scheduler.NewJob(index, nil, time.Hour)
+	s.AddJob(indexJob)
+	s.AddJob(fetchJob)
+	defer s.StopAll(time.Second)
+
+	assert.NoError(t, s.AddDependency(fetchJob, indexJob))
+	assert.Error(t, s.AddDependency(indexJob, fetchJob))
+	assert.Error(t, s.AddDependency(fetchJob, fetchJob))
+
+	stages := s.Pipeline()
+	assert.Equals(t, 2, len(stages))
+	assert.Equals(t, "testTask", stages[0].Name)
+	assert.Equals(t, []string{"failingTask"}, stages[0].Downstream)
+	assert.Equals(t, "failingTask", stages[1].Name)
+	assert.Equals(t, []string{"testTask"}, stages[1].Waiting)
+
+	// A successful upstream run triggers the downstream job.
+	fetchJob.RunNow()
+	time.Sleep(time.Millisecond * 30)
+	assert.Equals(t, 1, fetch.getCount())
+	assert.Equals(t, 1, index.getCount())
+
+	last, ok := indexJob.LastRecord()
+	assert.True(t, ok, "downstream job should have run")
+	assert.Equals(t, scheduler.DependencyTrigger, last.Trigger)
+
+	// Running the downstream job alone doesn't trigger anything.
+	indexJob.RunNow()
+	time.Sleep(time.Millisecond * 30)
+	assert.Equals(t, 1, fetch.getCount())
+	assert.Equals(t, 2, index.getCount())
+}
+
+// blockingLocker blocks every acquisition until it is released.
+type blockingLocker struct {
+	acquiring chan struct{}
+	release   chan struct{}
+}
+
+func (l *blockingLocker) Acquire(id int64, slot time.Time) (bool, error) {
+	l.acquiring <- struct{}{}
+	<-l.release
+	return true, nil
+}
+
+func TestDependencies_BusyDownstream(t *testing.T) {
+	locker := &blockingLocker{acquiring: make(chan struct{}, 1), release: make(chan struct{})}
+	fetch, index := &testTask{}, &testTask{}
+	s := scheduler.NewService()
+	fetchJob := scheduler.NewJob(fetch, nil, time.Hour)
+	indexJob := scheduler.New(index, scheduler.WithLocker(locker),
+		scheduler.WithSchedule(scheduler.StartImmediately(scheduler.Every(time.Hour))),
+		scheduler.WithOverlap(scheduler.OverlapPolicy{Mode: scheduler.OverlapQueue}))
+	s.AddJob(fetchJob)
+	s.AddJob(indexJob)
+	assert.NoError(t, s.AddDependency(fetchJob, indexJob))
+	<-locker.acquiring
+
+	// The upstream job finishes and stops while the downstream job is busy.
+	fetchJob.RunNow()
+	waitFor(t, func() bool { return fetch.getCount() == 1 })
+	stopped := make(chan struct{})
+	go func() {
+		fetchJob.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("upstream job blocked on its downstream job")
+	}
+
+	// The downstream job picks up the trigger once it is free again.
+	close(locker.release)
+	waitFor(t, func() bool { return index.getCount() == 2 })
+	assert.NoError(t, s.StopAll(time.Second))
+}
@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"time"
+)
+
+// EventType describes what happened to a job.
+type EventType int
+
+// All available event types.
+const (
+	// EventScheduled is emitted whenever the next run of a job was scheduled.
+	EventScheduled EventType = iota
+	// EventStarted is emitted when a task starts executing.
+	EventStarted
+	// EventSucceeded is emitted when a run finished without an error.
+	EventSucceeded
+	// EventFailed is emitted when a run finished with an error.
+	EventFailed
+	// EventSkippedInProgress is emitted when a trigger was dropped because the task is still running.
+	EventSkippedInProgress
+	// EventSkippedDisabled is emitted when a trigger was dropped because the job is paused.
+	EventSkippedDisabled
+	// EventIntervalChanged is emitted when the interval or schedule of a job was updated.
+	EventIntervalChanged
+	// EventStopped is emitted when a job was stopped.
+	EventStopped
+	// EventSkippedBlackout is emitted when a trigger was dropped because the job is blacked out.
+	EventSkippedBlackout
+	// EventSkippedRateLimited is emitted when a manual trigger was dropped because of the minimum spacing.
+	EventSkippedRateLimited
+	// EventSkippedClaimed is emitted when a scheduled slot was dropped because another instance claimed it.
+	EventSkippedClaimed
+)
+
+var (
+	eventTypeNames = map[EventType]string{
+		EventScheduled:          "Scheduled",
+		EventStarted:            "Started",
+		EventSucceeded:          "Succeeded",
+		EventFailed:             "Failed",
+		EventSkippedInProgress:  "SkippedInProgress",
+		EventSkippedDisabled:    "SkippedDisabled",
+		EventIntervalChanged:    "IntervalChanged",
+		EventStopped:            "Stopped",
+		EventSkippedBlackout:    "SkippedBlackout",
+		EventSkippedRateLimited: "SkippedRateLimited",
+		EventSkippedClaimed:     "SkippedClaimed",
+	}
+)
+
+// String returns the string representation of the given event type.
+func (t EventType) String() string {
+	return eventTypeNames[t]
+}
+
+// Event describes something which happened to a job. Depending on the type
+// of the event some fields are not set: NextRun is set for scheduled and
+// interval changed events, Trigger for started, succeeded and failed events
+// and Record and Err for succeeded and failed events.
+type Event struct {
+	Type    EventType
+	Job     *Job
+	Time    time.Time
+	NextRun time.Time
+	Trigger Trigger
+	Record  *RunRecord
+	Err     error
+}
+
+// Listener receives the events of a job. Listeners are called synchronously
+// from within the job and must therefore not block.
+type Listener func(Event)
+
+// ChannelListener returns a listener which sends all events to the
+// given channel. Events are dropped if the channel is full.
+func ChannelListener(ch chan<- Event) Listener {
+	return func(e Event) {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// AddListener registers a listener for the events of the job.
+func (j *Job) AddListener(l Listener) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.listeners = append(j.listeners, l)
+}
+
+// emit delivers the given event to all listeners, the mutex must not be held.
+func (j *Job) emit(e Event) {
+	j.mutex.Lock()
+	listeners := j.listeners
+	j.mutex.Unlock()
+
+	e.Job = j
+	if e.Time.IsZero() {
+		e.Time = j.now()
+	}
+	for _, l := range listeners {
+		l(e)
+	}
+}
+
+// AddListener registers a listener for the events of all
+// jobs of the service, including the ones added later on.
+func (s *Service) AddListener(l Listener) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.listeners = append(s.listeners, l)
+	for _, j := range s.jobs {
+		j.AddListener(l)
+	}
+}
@@ -0,0 +1,58 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+// expectEvents receives the given event types in order from the channel.
+func expectEvents(t *testing.T, events <-chan scheduler.Event, types ...scheduler.EventType) []scheduler.Event {
+	var received []scheduler.Event
+	for _, exp := range types {
+		select {
+		case e := <-events:
+			assert.Equals(t, exp.String(), e.Type.String())
+			received = append(received, e)
+		case <-time.After(time.Second):
+			t.Fatalf("Expected event %s, got nothing", exp)
+		}
+	}
+	return received
+}
+
+func TestEvents(t *testing.T) {
+	start := time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC)
+	clock := scheduler.NewFakeClock(start)
+	events := make(chan scheduler.Event, 100)
+
+	s := scheduler.NewService()
+	s.AddListener(scheduler.ChannelListener(events))
+	job := scheduler.NewJobWithClock(&testTask{}, nil, scheduler.Every(time.Hour), clock)
+	s.AddJob(job)
+
+	e := expectEvents(t, events, scheduler.EventScheduled)
+	assert.True(t, e[0].Job == job, "event should reference the job")
+	assert.Equals(t, start.Add(time.Hour), e[0].NextRun)
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	e = expectEvents(t, events, scheduler.EventScheduled, scheduler.EventStarted, scheduler.EventSucceeded)
+	assert.Equals(t, start.Add(2*time.Hour), e[0].NextRun)
+	assert.Equals(t, scheduler.TimerTrigger, e[1].Trigger)
+	assert.True(t, e[2].Record != nil, "succeeded event should contain the record")
+
+	job.Pause()
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	expectEvents(t, events, scheduler.EventScheduled, scheduler.EventSkippedDisabled)
+
+	job.UpdateInterval(time.Minute)
+	e = expectEvents(t, events, scheduler.EventIntervalChanged)
+	assert.Equals(t, start.Add(2*time.Hour+time.Minute), e[0].NextRun)
+
+	job.Stop()
+	expectEvents(t, events, scheduler.EventStopped)
+}
@@ -0,0 +1,144 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Handler implements an http.Handler which exposes the jobs of a service:
+//
+//	GET  /                lists all jobs
+//	GET  /{name}          returns a single job
+//	POST /{name}/pause    pauses the job
+//	POST /{name}/resume   resumes the job
+//	POST /{name}/run      triggers the job manually, 429 if the trigger was rejected
+//	POST /{name}/interval updates the interval, expects {"interval": "1h30m"}
+//
+// Jobs are identified by the name of their task. The handler does not set
+// any headers nor recover from panics, compose it with the middleware
+// package and mount it with http.StripPrefix, e.g.:
+//
+//	var h http.Handler = scheduler.NewHandler(service)
+//	h = middleware.NewContentType("application/json").Handler(h)
+//	h = middleware.NewRecoverer(logger).Handler(h)
+//	mux.Handle("/jobs/", http.StripPrefix("/jobs", h))
+type Handler struct {
+	service *Service
+}
+
+// NewHandler constructs a new handler for the given service.
+func NewHandler(s *Service) *Handler {
+	return &Handler{s}
+}
+
+type jobView struct {
+	Name       string    `json:"name"`
+	ID         int64     `json:"id"`
+	State      string    `json:"state"`
+	Interval   string    `json:"interval"`
+	Schedule   string    `json:"schedule"`
+	LastRun    time.Time `json:"lastRun"`
+	NextRun    time.Time `json:"nextRun"`
+	InProgress bool      `json:"inProgress"`
+}
+
+type intervalRequest struct {
+	Interval string `json:"interval"`
+}
+
+func newJobView(j *Job) jobView {
+	return jobView{
+		Name:       j.Name(),
+		ID:         j.ID(),
+		State:      j.State().String(),
+		Interval:   j.CurrentInterval().String(),
+		Schedule:   fmt.Sprint(j.CurrentSchedule()),
+		LastRun:    j.LastRun(),
+		NextRun:    j.NextRun(),
+		InProgress: j.InProgress(),
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	if path == "" {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w)
+			return
+		}
+		views := []jobView{}
+		for _, j := range h.service.Jobs() {
+			views = append(views, newJobView(j))
+		}
+		writeJSON(w, views)
+		return
+	}
+
+	name, action := path, ""
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		name, action = path[:i], path[i+1:]
+	}
+	j, ok := h.service.JobByName(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("job %q not found", name), http.StatusNotFound)
+		return
+	}
+
+	if action == "" {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w)
+			return
+		}
+		writeJSON(w, newJobView(j))
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+	switch action {
+	case "pause":
+		j.Pause()
+	case "resume":
+		j.Resume()
+	case "run":
+		if !j.TryRunNow() {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+	case "interval":
+		var req intervalRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("could not decode request: %v", err), http.StatusBadRequest)
+			return
+		}
+		d, err := time.ParseDuration(req.Interval)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("invalid interval %q", req.Interval), http.StatusBadRequest)
+			return
+		}
+		j.UpdateInterval(d)
+	default:
+		http.Error(w, fmt.Sprintf("unknown action %q", action), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not marshal response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Write(bytes)
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
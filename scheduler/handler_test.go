@@ -0,0 +1,60 @@
+package scheduler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/middleware"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+func TestHandler(t *testing.T) {
+	s := scheduler.NewService()
+	task := &testTask{}
+	job := scheduler.NewJob(task, nil, time.Hour)
+	s.AddJob(job)
+	defer job.Stop()
+
+	h := middleware.NewContentType("application/json").Handler(scheduler.NewHandler(s))
+	server := httptest.NewServer(http.StripPrefix("/jobs", h))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/jobs/")
+	assert.NoError(t, err)
+	var jobs []map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&jobs))
+	resp.Body.Close()
+	assert.Equals(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Equals(t, 1, len(jobs))
+	assert.Equals(t, "testTask", jobs[0]["name"])
+	assert.Equals(t, "Enabled", jobs[0]["state"])
+	assert.Equals(t, "1h0m0s", jobs[0]["interval"])
+
+	resp, err = http.Post(server.URL+"/jobs/testTask/pause", "", nil)
+	assert.NoError(t, err)
+	assert.Equals(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equals(t, scheduler.Disabled, job.State())
+
+	resp, err = http.Post(server.URL+"/jobs/testTask/interval", "application/json", strings.NewReader(`{"interval": "30m"}`))
+	assert.NoError(t, err)
+	assert.Equals(t, http.StatusNoContent, resp.StatusCode)
+	time.Sleep(time.Millisecond * 15)
+	assert.Equals(t, 30*time.Minute, job.CurrentInterval())
+
+	resp, err = http.Post(server.URL+"/jobs/testTask/interval", "application/json", strings.NewReader(`{"interval": "soon"}`))
+	assert.NoError(t, err)
+	assert.Equals(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Post(server.URL+"/jobs/unknown/run", "", nil)
+	assert.NoError(t, err)
+	assert.Equals(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/jobs/testTask/run")
+	assert.NoError(t, err)
+	assert.Equals(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
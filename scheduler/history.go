@@ -0,0 +1,233 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultHistorySize is the number of runs a job remembers by default.
+const DefaultHistorySize = 100
+
+// Trigger describes what caused a run.
+type Trigger int
+
+// All available triggers.
+const (
+	TimerTrigger Trigger = iota
+	ManualTrigger
+	CatchUpTrigger
+	DependencyTrigger
+	PayloadTrigger
+)
+
+var (
+	triggerNames = map[Trigger]string{
+		TimerTrigger:      "Timer",
+		ManualTrigger:     "Manual",
+		CatchUpTrigger:    "CatchUp",
+		DependencyTrigger: "Dependency",
+		PayloadTrigger:    "Payload",
+	}
+)
+
+// String returns the string representation of the given trigger.
+func (t Trigger) String() string {
+	return triggerNames[t]
+}
+
+// RunRecord describes a single run of a job.
+type RunRecord struct {
+	Start    time.Time
+	End      time.Time
+	Duration time.Duration
+	Wait     time.Duration
+	Err      error
+	Trigger  Trigger
+	Attempts int
+	Payload  interface{}
+}
+
+// Succeeded returns whether the run finished without an error.
+func (r RunRecord) Succeeded() bool {
+	return r.Err == nil
+}
+
+// Stats contains aggregated statistics about the runs of a job.
+// The counts cover the whole lifetime of the job, the durations
+// only the runs which are still in the job's history.
+type Stats struct {
+	Runs         int
+	Successes    int
+	Failures     int
+	MeanDuration time.Duration
+	P95Duration  time.Duration
+}
+
+// JobSummary summarizes the state and statistics of a job.
+type JobSummary struct {
+	Name       string
+	ID         int64
+	State      State
+	InProgress bool
+	LastRun    time.Time
+	NextRun    time.Time
+	Last       *RunRecord
+	Stats      Stats
+}
+
+// runHistory is a ring buffer of run records plus lifetime counters.
+type runHistory struct {
+	records   []RunRecord
+	next      int
+	full      bool
+	runs      int
+	successes int
+	failures  int
+
+	// Lifetime histogram of the run durations, see metricBuckets.
+	buckets     []int
+	durationSum time.Duration
+}
+
+func newRunHistory(size int) *runHistory {
+	if size < 1 {
+		size = 1
+	}
+	return &runHistory{
+		records: make([]RunRecord, size),
+		buckets: make([]int, len(metricBuckets)),
+	}
+}
+
+func (h *runHistory) add(r RunRecord) {
+	h.records[h.next] = r
+	h.next = (h.next + 1) % len(h.records)
+	if h.next == 0 {
+		h.full = true
+	}
+	h.runs++
+	h.durationSum += r.Duration
+	for i, le := range metricBuckets {
+		if r.Duration.Seconds() <= le {
+			h.buckets[i]++
+		}
+	}
+	if r.Succeeded() {
+		h.successes++
+	} else {
+		h.failures++
+	}
+}
+
+// list returns the records ordered from oldest to newest.
+func (h *runHistory) list() []RunRecord {
+	if !h.full {
+		return append([]RunRecord(nil), h.records[:h.next]...)
+	}
+	return append(append([]RunRecord(nil), h.records[h.next:]...), h.records[:h.next]...)
+}
+
+// last returns the newest record, if there is one.
+func (h *runHistory) last() (RunRecord, bool) {
+	if !h.full && h.next == 0 {
+		return RunRecord{}, false
+	}
+	return h.records[(h.next-1+len(h.records))%len(h.records)], true
+}
+
+// resize changes the capacity of the buffer keeping the newest records.
+func (h *runHistory) resize(size int) {
+	if size < 1 {
+		size = 1
+	}
+	records := h.list()
+	if len(records) > size {
+		records = records[len(records)-size:]
+	}
+	h.records = make([]RunRecord, size)
+	copy(h.records, records)
+	h.next = len(records) % size
+	h.full = len(records) == size
+}
+
+func (h *runHistory) stats() Stats {
+	stats := Stats{
+		Runs:      h.runs,
+		Successes: h.successes,
+		Failures:  h.failures,
+	}
+	records := h.list()
+	if len(records) == 0 {
+		return stats
+	}
+
+	durations := make([]time.Duration, len(records))
+	var total time.Duration
+	for i, r := range records {
+		durations[i] = r.Duration
+		total += r.Duration
+	}
+	sort.Slice(durations, func(a, b int) bool { return durations[a] < durations[b] })
+
+	// Nearest rank percentile.
+	rank := (95*len(durations) + 99) / 100
+	stats.MeanDuration = total / time.Duration(len(durations))
+	stats.P95Duration = durations[rank-1]
+	return stats
+}
+
+// SetHistorySize sets how many runs the job remembers, the newest are kept.
+func (j *Job) SetHistorySize(n int) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.history.resize(n)
+}
+
+// History returns the remembered runs of the job, oldest first.
+func (j *Job) History() []RunRecord {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.history.list()
+}
+
+// LastRecord returns the record of the last finished run, if there is one.
+func (j *Job) LastRecord() (RunRecord, bool) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.history.last()
+}
+
+// Stats returns aggregated statistics about the runs of the job.
+func (j *Job) Stats() Stats {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.history.stats()
+}
+
+// Summary returns a summary of the job's state and statistics.
+func (j *Job) Summary() JobSummary {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	summary := JobSummary{
+		Name:       j.Name(),
+		ID:         j.ID(),
+		State:      j.state,
+		InProgress: j.running > 0,
+		LastRun:    j.lastRun,
+		NextRun:    j.nextRun,
+		Stats:      j.history.stats(),
+	}
+	if last, ok := j.history.last(); ok {
+		summary.Last = &last
+	}
+	return summary
+}
+
+// Summaries returns a summary of every job of the service.
+func (s *Service) Summaries() []JobSummary {
+	var summaries []JobSummary
+	for _, j := range s.Jobs() {
+		summaries = append(summaries, j.Summary())
+	}
+	return summaries
+}
@@ -0,0 +1,44 @@
+package scheduler_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+func TestHistory(t *testing.T) {
+	task := &failingTask{failures: 2, err: errors.New("failure")}
+
+	job := scheduler.NewJob(task, nil, time.Hour)
+	job.SetHistorySize(3)
+	for i := 0; i < 5; i++ {
+		job.RunNow()
+		time.Sleep(time.Millisecond * 10)
+	}
+	job.Stop()
+
+	history := job.History()
+	assert.Equals(t, 3, len(history))
+	for _, r := range history {
+		assert.True(t, r.Succeeded(), "remembered runs should have succeeded")
+		assert.Equals(t, scheduler.ManualTrigger, r.Trigger)
+		assert.Equals(t, r.End.Sub(r.Start), r.Duration)
+	}
+
+	stats := job.Stats()
+	assert.Equals(t, 5, stats.Runs)
+	assert.Equals(t, 3, stats.Successes)
+	assert.Equals(t, 2, stats.Failures)
+	assert.True(t, stats.P95Duration >= stats.MeanDuration, "p95 should not be below the mean")
+
+	s := scheduler.NewService()
+	s.AddJob(job)
+	summaries := s.Summaries()
+	assert.Equals(t, 1, len(summaries))
+	assert.Equals(t, "failingTask", summaries[0].Name)
+	assert.Equals(t, stats, summaries[0].Stats)
+	assert.True(t, summaries[0].Last != nil, "summary should contain the last run")
+}
@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locker interface describes a lock shared by all instances of a service.
+// It makes sure only one instance executes a scheduled slot of a task. Slots
+// of interval schedules are aligned to multiples of the interval first, so
+// instances which started at different times still claim the same slots.
+type Locker interface {
+	// Acquire claims the given slot of the task with the given ID.
+	// It returns false if another instance claimed the slot already.
+	Acquire(id int64, slot time.Time) (bool, error)
+}
+
+// lockRetention is how long the FileLocker keeps claims of past slots.
+const lockRetention = 24 * time.Hour
+
+// FileLocker implements a Locker using lock files in a directory, which
+// is suitable for multiple instances running on the same host.
+type FileLocker struct {
+	dir string
+}
+
+// NewFileLocker constructs a new file locker, the directory is created if necessary.
+func NewFileLocker(dir string) (*FileLocker, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("could not create lock directory: %v", err)
+	}
+	return &FileLocker{dir}, nil
+}
+
+// Acquire claims the slot by exclusively creating a lock file.
+func (l *FileLocker) Acquire(id int64, slot time.Time) (bool, error) {
+	path := filepath.Join(l.dir, fmt.Sprintf("%d-%d.lock", id, slot.Unix()))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if os.IsExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("could not create lock file: %v", err)
+	}
+	fmt.Fprintf(file, "%d", os.Getpid())
+	file.Close()
+
+	l.cleanup(id, slot.Add(-lockRetention))
+	return true, nil
+}
+
+// cleanup removes the lock files of the given task older than the given time.
+func (l *FileLocker) cleanup(id int64, before time.Time) {
+	files, err := ioutil.ReadDir(l.dir)
+	if err != nil {
+		return
+	}
+	prefix := fmt.Sprintf("%d-", id)
+	for _, f := range files {
+		name := f.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".lock") {
+			continue
+		}
+		unix, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".lock"), 10, 64)
+		if err == nil && time.Unix(unix, 0).Before(before) {
+			os.Remove(filepath.Join(l.dir, name))
+		}
+	}
+}
+
+// SetLocker sets the locker consulted before every scheduled run, nil removes it.
+// Manual triggers are local to an instance and don't consult the locker.
+func (j *Job) SetLocker(l Locker) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.locker = l
+}
+
+// runSlot runs the job for the given scheduled slot, unless another
+// instance claimed the slot already.
+func (j *Job) runSlot(trigger Trigger, slot time.Time) {
+	if j.State() == Disabled {
+		j.info("JOB=%s Job is disabled.", j.Name())
+		j.emit(Event{Type: EventSkippedDisabled, Trigger: trigger})
+		return
+	}
+
+	j.mutex.Lock()
+	locker := j.locker
+	key := lockSlot(j.curSchedule, slot)
+	j.mutex.Unlock()
+	if locker != nil {
+		ok, err := locker.Acquire(j.ID(), key)
+		if err != nil {
+			j.error("JOB=%s Could not acquire lock for %s: %v.", j.Name(), slot.Format(time.RFC3339), err)
+			return
+		}
+		if !ok {
+			j.info("JOB=%s Slot %s is claimed by another instance.", j.Name(), slot.Format(time.RFC3339))
+			j.emit(Event{Type: EventSkippedClaimed, Trigger: trigger})
+			return
+		}
+	}
+	j.run(trigger)
+}
+
+// lockSlot returns the slot under which the given slot of the schedule is
+// claimed. Interval schedules depend on when an instance started, their
+// slots are truncated to the interval to match across instances.
+func lockSlot(s Schedule, slot time.Time) time.Time {
+	if interval := intervalOf(s); interval > 0 {
+		return slot.Truncate(interval)
+	}
+	return slot
+}
@@ -0,0 +1,72 @@
+package scheduler_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+func TestFileLocker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scheduler-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	locker, err := scheduler.NewFileLocker(filepath.Join(dir, "locks"))
+	assert.NoError(t, err)
+
+	// Two replicas of the same job share the locker, only one of them runs.
+	start := time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC)
+	clock := scheduler.NewFakeClock(start)
+	first, second := &testTask{}, &testTask{}
+	firstJob := scheduler.NewJobWithClock(first, nil, scheduler.Every(time.Hour), clock)
+	secondJob := scheduler.NewJobWithClock(second, nil, scheduler.Every(time.Hour), clock)
+	firstJob.SetLocker(locker)
+	secondJob.SetLocker(locker)
+
+	clock.BlockUntil(2)
+	clock.Advance(time.Hour)
+	clock.BlockUntil(2)
+	firstJob.Stop()
+	secondJob.Stop()
+
+	assert.Equals(t, 1, first.getCount()+second.getCount())
+
+	ok, err := locker.Acquire(0, start.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, !ok, "slot should be claimed already")
+	ok, err = locker.Acquire(0, start.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, ok, "next slot should be free")
+}
+
+func TestFileLocker_Unaligned(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scheduler-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	locker, err := scheduler.NewFileLocker(filepath.Join(dir, "locks"))
+	assert.NoError(t, err)
+
+	// Replicas starting at different times still share their slots.
+	clock := scheduler.NewFakeClock(time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC))
+	first, second := &testTask{}, &testTask{}
+	firstJob := scheduler.NewJobWithClock(first, nil, scheduler.Every(time.Hour), clock)
+	firstJob.SetLocker(locker)
+	clock.BlockUntil(1)
+	clock.Advance(20 * time.Second)
+	secondJob := scheduler.NewJobWithClock(second, nil, scheduler.Every(time.Hour), clock)
+	secondJob.SetLocker(locker)
+
+	clock.BlockUntil(2)
+	clock.Advance(time.Hour)
+	clock.BlockUntil(2)
+	firstJob.Stop()
+	secondJob.Stop()
+
+	assert.Equals(t, 1, first.getCount()+second.getCount())
+}
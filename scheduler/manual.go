@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"time"
+)
+
+// ManualTriggerPolicy limits how often manual triggers run a job.
+type ManualTriggerPolicy struct {
+	// Debounce collapses all manual triggers within the given window,
+	// starting with the first one, into a single run at its end.
+	Debounce time.Duration
+	// MinSpacing is the minimum time between the start of two manual runs.
+	// Earlier triggers are rejected, debounced runs are delayed instead.
+	MinSpacing time.Duration
+}
+
+// SetManualTriggerPolicy sets how often manual triggers run the job.
+func (j *Job) SetManualTriggerPolicy(p ManualTriggerPolicy) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.manualPolicy = p
+}
+
+// ManualTriggerPolicy returns how often manual triggers run the job.
+func (j *Job) ManualTriggerPolicy() ManualTriggerPolicy {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.manualPolicy
+}
+
+// TryRunNow triggers the job manually without blocking. It returns false if
+// the trigger was rejected, because another one is still waiting to be picked
+// up by the job or because the minimum spacing between manual runs has not
+// passed yet, or because the job was stopped.
+func (j *Job) TryRunNow() bool {
+	if j.stopping() {
+		return false
+	}
+	j.mutex.Lock()
+	rejected := j.manualPolicy.Debounce <= 0 && j.manualWait(j.now()) > 0
+	j.mutex.Unlock()
+	if rejected {
+		return false
+	}
+
+	select {
+	case j.runNowAsync <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// manualWait returns how long manual runs have to wait for the
+// minimum spacing to pass. The mutex must be held.
+func (j *Job) manualWait(now time.Time) time.Duration {
+	if j.manualPolicy.MinSpacing <= 0 || j.lastManual.IsZero() {
+		return 0
+	}
+	return j.lastManual.Add(j.manualPolicy.MinSpacing).Sub(now)
+}
+
+// manualTrigger handles a manual trigger according to the manual trigger
+// policy, it must only be called from within the job's go routine.
+func (j *Job) manualTrigger(debounce Timer) {
+	now := j.now()
+	j.mutex.Lock()
+	switch {
+	case j.debouncing:
+		j.mutex.Unlock()
+		j.info("JOB=%s Coalescing manual trigger.", j.Name())
+		return
+	case j.manualPolicy.Debounce > 0:
+		j.debouncing = true
+		j.resetRunTimer(debounce, now.Add(j.manualPolicy.Debounce))
+		j.mutex.Unlock()
+		j.info("JOB=%s Debouncing manual trigger for %s.", j.Name(), j.manualPolicy.Debounce)
+		return
+	case j.manualWait(now) > 0:
+		j.mutex.Unlock()
+		j.info("JOB=%s Manual trigger within minimum spacing.", j.Name())
+		j.emit(Event{Type: EventSkippedRateLimited, Trigger: ManualTrigger})
+		return
+	}
+	j.lastManual = now
+	j.mutex.Unlock()
+	j.run(ManualTrigger)
+}
+
+// debounced runs the job once the debounce window of manual triggers ended,
+// it must only be called from within the job's go routine.
+func (j *Job) debounced(debounce Timer) {
+	now := j.now()
+	j.mutex.Lock()
+	if wait := j.manualWait(now); wait > 0 {
+		j.resetRunTimer(debounce, now.Add(wait))
+		j.mutex.Unlock()
+		return
+	}
+	j.debouncing = false
+	j.lastManual = now
+	j.mutex.Unlock()
+	j.run(ManualTrigger)
+}
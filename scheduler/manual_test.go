@@ -0,0 +1,51 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+func TestTryRunNow_MinSpacing(t *testing.T) {
+	clock := scheduler.NewFakeClock(time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC))
+	task := &testTask{}
+	job := scheduler.New(task,
+		scheduler.WithInterval(time.Hour),
+		scheduler.WithClock(clock),
+		scheduler.WithManualTriggerPolicy(scheduler.ManualTriggerPolicy{MinSpacing: time.Minute}),
+	)
+	defer job.Stop()
+
+	assert.True(t, job.TryRunNow(), "first trigger should be accepted")
+	waitFor(t, func() bool { return task.getCount() == 1 })
+	assert.True(t, !job.TryRunNow(), "trigger within the minimum spacing should be rejected")
+
+	clock.Advance(time.Minute)
+	assert.True(t, job.TryRunNow(), "trigger after the minimum spacing should be accepted")
+	waitFor(t, func() bool { return task.getCount() == 2 })
+}
+
+func TestTryRunNow_Debounce(t *testing.T) {
+	clock := scheduler.NewFakeClock(time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC))
+	task := &testTask{}
+	job := scheduler.New(task,
+		scheduler.WithInterval(time.Hour),
+		scheduler.WithClock(clock),
+		scheduler.WithManualTriggerPolicy(scheduler.ManualTriggerPolicy{Debounce: time.Second}),
+	)
+	defer job.Stop()
+	clock.BlockUntil(1)
+
+	// A burst of triggers results in a single run at the end of the window.
+	for i := 0; i < 10; i++ {
+		job.RunNow()
+	}
+	clock.BlockUntil(2)
+	assert.Equals(t, 0, task.getCount())
+	clock.Advance(time.Second)
+	waitFor(t, func() bool { return task.getCount() == 1 })
+	time.Sleep(time.Millisecond * 10)
+	assert.Equals(t, 1, task.getCount())
+}
@@ -0,0 +1,163 @@
+package scheduler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metricBuckets are the upper bounds of the duration histogram in seconds.
+var metricBuckets = []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 1800, 3600}
+
+// Collector renders metrics about the jobs of a service
+// in the Prometheus text exposition format.
+type Collector struct {
+	service *Service
+}
+
+// NewCollector constructs a new collector for the given service.
+func NewCollector(s *Service) *Collector {
+	return &Collector{s}
+}
+
+// jobMetrics is a consistent snapshot of the metrics of a job.
+type jobMetrics struct {
+	name        string
+	runs        int
+	failures    int
+	buckets     []int
+	durationSum time.Duration
+	inProgress  bool
+	state       State
+	lastRun     time.Time
+	nextRun     time.Time
+}
+
+func (j *Job) metrics() jobMetrics {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return jobMetrics{
+		name:        j.Name(),
+		runs:        j.history.runs,
+		failures:    j.history.failures,
+		buckets:     append([]int(nil), j.history.buckets...),
+		durationSum: j.history.durationSum,
+		inProgress:  j.running > 0,
+		state:       j.state,
+		lastRun:     j.lastRun,
+		nextRun:     j.nextRun,
+	}
+}
+
+// WriteTo writes the metrics of all jobs to the given writer.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	var metrics []jobMetrics
+	for _, j := range c.service.Jobs() {
+		metrics = append(metrics, j.metrics())
+	}
+
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+	family := func(name, typ, help string, value func(m jobMetrics) float64) {
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		for _, m := range metrics {
+			fmt.Fprintf(cw, "%s{job=\"%s\"} %s\n", name, escapeLabel(m.name), formatFloat(value(m)))
+		}
+	}
+
+	family("scheduler_job_runs_total", "counter", "Total number of finished runs.", func(m jobMetrics) float64 {
+		return float64(m.runs)
+	})
+	family("scheduler_job_failures_total", "counter", "Total number of failed runs.", func(m jobMetrics) float64 {
+		return float64(m.failures)
+	})
+
+	fmt.Fprintf(cw, "# HELP scheduler_job_duration_seconds Duration of finished runs.\n")
+	fmt.Fprintf(cw, "# TYPE scheduler_job_duration_seconds histogram\n")
+	for _, m := range metrics {
+		label := escapeLabel(m.name)
+		for i, le := range metricBuckets {
+			fmt.Fprintf(cw, "scheduler_job_duration_seconds_bucket{job=\"%s\",le=\"%s\"} %d\n", label, formatFloat(le), m.buckets[i])
+		}
+		fmt.Fprintf(cw, "scheduler_job_duration_seconds_bucket{job=\"%s\",le=\"+Inf\"} %d\n", label, m.runs)
+		fmt.Fprintf(cw, "scheduler_job_duration_seconds_sum{job=\"%s\"} %s\n", label, formatFloat(m.durationSum.Seconds()))
+		fmt.Fprintf(cw, "scheduler_job_duration_seconds_count{job=\"%s\"} %d\n", label, m.runs)
+	}
+
+	family("scheduler_job_in_progress", "gauge", "Whether a run of the job is in progress.", func(m jobMetrics) float64 {
+		return boolToFloat(m.inProgress)
+	})
+	family("scheduler_job_enabled", "gauge", "Whether the job is enabled.", func(m jobMetrics) float64 {
+		return boolToFloat(m.state == Enabled)
+	})
+	family("scheduler_job_last_run_timestamp_seconds", "gauge", "Start of the last run as unix timestamp.", func(m jobMetrics) float64 {
+		return unixSeconds(m.lastRun)
+	})
+	family("scheduler_job_next_run_timestamp_seconds", "gauge", "Next scheduled run as unix timestamp.", func(m jobMetrics) float64 {
+		return unixSeconds(m.nextRun)
+	})
+
+	if pool := c.service.Pool(); pool != nil {
+		stats := pool.Stats()
+		fmt.Fprintf(cw, "# HELP scheduler_pool_running Number of tasks running in the pool.\n")
+		fmt.Fprintf(cw, "# TYPE scheduler_pool_running gauge\nscheduler_pool_running %d\n", stats.Running)
+		fmt.Fprintf(cw, "# HELP scheduler_pool_queue_length Number of runs waiting for the pool.\n")
+		fmt.Fprintf(cw, "# TYPE scheduler_pool_queue_length gauge\nscheduler_pool_queue_length %d\n", stats.Queued)
+	}
+
+	if cw.err == nil {
+		cw.err = bw.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	c.WriteTo(w)
+}
+
+// countingWriter counts the written bytes and remembers the first error.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / 1e9
+}
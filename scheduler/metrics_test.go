@@ -0,0 +1,53 @@
+package scheduler_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+func TestCollector(t *testing.T) {
+	start := time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC)
+	clock := scheduler.NewFakeClock(start)
+	events := make(chan scheduler.Event, 100)
+
+	s := scheduler.NewService()
+	s.AddListener(scheduler.ChannelListener(events))
+	job := scheduler.NewJobWithClock(&failingTask{failures: 1, err: errors.New("failure")}, nil, scheduler.Every(time.Hour), clock)
+	s.AddJob(job)
+	defer job.Stop()
+
+	expectEvents(t, events, scheduler.EventScheduled)
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	expectEvents(t, events, scheduler.EventScheduled, scheduler.EventStarted, scheduler.EventFailed)
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	expectEvents(t, events, scheduler.EventScheduled, scheduler.EventStarted, scheduler.EventSucceeded)
+
+	w := httptest.NewRecorder()
+	scheduler.NewCollector(s).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := ioutil.ReadAll(w.Body)
+	assert.NoError(t, err)
+
+	for _, line := range []string{
+		"# TYPE scheduler_job_runs_total counter",
+		`scheduler_job_runs_total{job="failingTask"} 2`,
+		`scheduler_job_failures_total{job="failingTask"} 1`,
+		"# TYPE scheduler_job_duration_seconds histogram",
+		`scheduler_job_duration_seconds_bucket{job="failingTask",le="0.01"} 2`,
+		`scheduler_job_duration_seconds_bucket{job="failingTask",le="+Inf"} 2`,
+		`scheduler_job_duration_seconds_sum{job="failingTask"} 0`,
+		`scheduler_job_duration_seconds_count{job="failingTask"} 2`,
+		`scheduler_job_in_progress{job="failingTask"} 0`,
+		`scheduler_job_next_run_timestamp_seconds{job="failingTask"} 1552654800`,
+	} {
+		assert.True(t, strings.Contains(string(body), line+"\n"), "metrics should contain %q", line)
+	}
+}
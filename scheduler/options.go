@@ -0,0 +1,149 @@
+package scheduler
+
+import (
+	"time"
+)
+
+// Option configures a job created by New or NewContext.
+type Option func(*Job)
+
+// New creates a new job for the given task, configured by the given options.
+// Without options the job is quiet, uses the system time and only runs when
+// triggered, e.g. by RunNow or an upstream job.
+func New(task Task, opts ...Option) *Job {
+	return newJob(task, taskAdapter{task}, opts)
+}
+
+// NewContext creates a new job for the given cancellable task, see New.
+func NewContext(task ContextTask, opts ...Option) *Job {
+	return newJob(contextTaskAdapter{task}, task, opts)
+}
+
+// WithLogger makes the job log to the given logger, if that is nil the job will be quiet.
+func WithLogger(logger Logger) Option {
+	return func(j *Job) {
+		j.Logger = logger
+	}
+}
+
+// WithInterval makes the job run every d, it panics if d is not positive.
+func WithInterval(d time.Duration) Option {
+	return WithSchedule(Every(d))
+}
+
+// WithSchedule makes the job run according to the given schedule, e.g. one returned by ParseCron.
+func WithSchedule(s Schedule) Option {
+	return func(j *Job) {
+		if s != nil {
+			j.curSchedule = s
+		}
+	}
+}
+
+// WithLocation makes the job evaluate its schedule in the given location,
+// e.g. to run a cron schedule at local midnight. It defaults to UTC.
+func WithLocation(loc *time.Location) Option {
+	return func(j *Job) {
+		if loc != nil {
+			j.location = loc
+		}
+	}
+}
+
+// WithClock makes the job take the time from the given clock, e.g. a FakeClock.
+// If the clock is nil the system time is used.
+func WithClock(clock Clock) Option {
+	return func(j *Job) {
+		if clock != nil {
+			j.clock = clock
+		}
+	}
+}
+
+// WithTimeout sets the maximum duration of a single attempt, see SetTimeout.
+func WithTimeout(d time.Duration) Option {
+	return func(j *Job) {
+		j.SetTimeout(d)
+	}
+}
+
+// WithRetry sets the policy for retrying failed runs, see SetRetryPolicy.
+func WithRetry(p RetryPolicy) Option {
+	return func(j *Job) {
+		j.SetRetryPolicy(&p)
+	}
+}
+
+// WithOverlap sets how the job handles triggers while its task is
+// still running, see SetOverlapPolicy.
+func WithOverlap(p OverlapPolicy) Option {
+	return func(j *Job) {
+		j.SetOverlapPolicy(p)
+	}
+}
+
+// WithJitter sets the maximum random delay added to every scheduled run,
+// including the first one, see SetJitter.
+func WithJitter(max time.Duration) Option {
+	return func(j *Job) {
+		j.SetJitter(max)
+	}
+}
+
+// WithHistorySize sets how many runs the job remembers, see SetHistorySize.
+func WithHistorySize(n int) Option {
+	return func(j *Job) {
+		j.SetHistorySize(n)
+	}
+}
+
+// WithGroup sets the pool group of the job, see SetGroup.
+func WithGroup(group string) Option {
+	return func(j *Job) {
+		j.SetGroup(group)
+	}
+}
+
+// WithPriority sets the priority of the job's runs, see SetPriority.
+func WithPriority(priority int) Option {
+	return func(j *Job) {
+		j.SetPriority(priority)
+	}
+}
+
+// WithLocker makes the job consult the given locker before scheduled runs, see SetLocker.
+func WithLocker(l Locker) Option {
+	return func(j *Job) {
+		j.SetLocker(l)
+	}
+}
+
+// WithManualTriggerPolicy sets how often manual triggers
+// run the job, see SetManualTriggerPolicy.
+func WithManualTriggerPolicy(p ManualTriggerPolicy) Option {
+	return func(j *Job) {
+		j.manualPolicy = p
+	}
+}
+
+// WithPayloadQueueSize sets the number of pending payloads
+// the job accepts, see SetPayloadQueueSize.
+func WithPayloadQueueSize(n int) Option {
+	return func(j *Job) {
+		j.payloadQueueSize = n
+	}
+}
+
+// WithBlackout sets when the job must not run, see SetBlackout.
+func WithBlackout(b *Blackout) Option {
+	return func(j *Job) {
+		j.blackout = b
+	}
+}
+
+// StartPaused creates the job in paused state, it does not run until resumed.
+func StartPaused() Option {
+	return func(j *Job) {
+		j.state = Disabled
+	}
+}
@@ -0,0 +1,59 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+func TestNew(t *testing.T) {
+	start := time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC)
+	clock := scheduler.NewFakeClock(start)
+	overlap := scheduler.OverlapPolicy{Mode: scheduler.OverlapQueue}
+	job := scheduler.New(&testTask{},
+		scheduler.WithInterval(time.Minute),
+		scheduler.WithClock(clock),
+		scheduler.WithTimeout(time.Second),
+		scheduler.WithRetry(scheduler.RetryPolicy{MaxAttempts: 3}),
+		scheduler.WithOverlap(overlap),
+		scheduler.StartPaused(),
+	)
+	defer job.Stop()
+	clock.BlockUntil(1)
+
+	assert.Equals(t, time.Minute, job.CurrentInterval())
+	assert.Equals(t, start.Add(time.Minute), job.NextRun())
+	assert.Equals(t, time.Second, job.Timeout())
+	assert.Equals(t, 3, job.RetryPolicy().MaxAttempts)
+	assert.Equals(t, overlap, job.OverlapPolicy())
+	assert.Equals(t, scheduler.Disabled, job.State())
+}
+
+func TestNew_Manual(t *testing.T) {
+	task := &testTask{}
+	job := scheduler.New(task)
+	defer job.Stop()
+
+	// Without a schedule the job only runs when triggered.
+	assert.True(t, job.NextRun().IsZero(), "job should not be scheduled")
+	job.RunNow()
+	waitFor(t, func() bool { return task.getCount() == 1 })
+}
+
+func TestNew_Location(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	clock := scheduler.NewFakeClock(time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC))
+	job := scheduler.New(&testTask{},
+		scheduler.WithSchedule(scheduler.MustParseCron("@daily")),
+		scheduler.WithLocation(berlin),
+		scheduler.WithClock(clock),
+	)
+	defer job.Stop()
+	clock.BlockUntil(1)
+
+	assert.Equals(t, berlin, job.Location())
+	assert.True(t, job.NextRun().Equal(time.Date(2019, time.March, 16, 0, 0, 0, 0, berlin)), "job should run at local midnight")
+}
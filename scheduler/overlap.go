@@ -0,0 +1,74 @@
+package scheduler
+
+// OverlapMode decides what happens when a job is triggered while its task is still running.
+type OverlapMode int
+
+// All available overlap modes.
+const (
+	// OverlapSkip drops the trigger, this is the default.
+	OverlapSkip OverlapMode = iota
+	// OverlapQueue remembers exactly one trigger and runs it once the
+	// running task finished. Further triggers are dropped meanwhile.
+	OverlapQueue
+	// OverlapAllow runs up to Limit tasks concurrently, triggers beyond are dropped.
+	OverlapAllow
+	// OverlapReplace cancels the running task and starts a fresh one once it returned.
+	OverlapReplace
+)
+
+var (
+	overlapModeNames = map[OverlapMode]string{
+		OverlapSkip:    "Skip",
+		OverlapQueue:   "Queue",
+		OverlapAllow:   "Allow",
+		OverlapReplace: "Replace",
+	}
+)
+
+// String returns the string representation of the given overlap mode.
+func (m OverlapMode) String() string {
+	return overlapModeNames[m]
+}
+
+// OverlapPolicy describes how a job handles overlapping runs.
+type OverlapPolicy struct {
+	Mode OverlapMode
+	// Limit is the maximum number of concurrent runs for OverlapAllow.
+	Limit int
+}
+
+// limit returns the maximum number of concurrent runs.
+func (p OverlapPolicy) limit() int {
+	if p.Mode == OverlapAllow && p.Limit > 1 {
+		return p.Limit
+	}
+	return 1
+}
+
+// SetOverlapPolicy sets how the job handles triggers while its task is still running.
+func (j *Job) SetOverlapPolicy(p OverlapPolicy) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.overlap = p
+}
+
+// OverlapPolicy returns how the job handles triggers while its task is still running.
+func (j *Job) OverlapPolicy() OverlapPolicy {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.overlap
+}
+
+// Running returns the number of currently running tasks.
+func (j *Job) Running() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.running
+}
+
+// Pending returns whether a trigger is waiting for the running task to finish.
+func (j *Job) Pending() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.pending
+}
@@ -0,0 +1,61 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+func TestOverlap_Queue(t *testing.T) {
+	task := &blockingTask{}
+	job := scheduler.NewContextJob(task, nil, scheduler.Every(time.Hour))
+	job.SetOverlapPolicy(scheduler.OverlapPolicy{Mode: scheduler.OverlapQueue})
+	defer job.Stop()
+
+	job.RunNow()
+	job.RunNow()
+	job.RunNow()
+	waitFor(t, func() bool { return job.Pending() })
+	assert.Equals(t, 1, job.Running())
+
+	// Once the first run finished, exactly one queued run follows.
+	job.Cancel()
+	waitFor(t, func() bool { return !job.Pending() && job.Running() == 1 })
+	job.Cancel()
+	waitFor(t, func() bool { return !job.InProgress() })
+	assert.Equals(t, 2, task.getCancelled())
+	assert.Equals(t, 2, job.Stats().Runs)
+}
+
+func TestOverlap_Allow(t *testing.T) {
+	task := &blockingTask{}
+	job := scheduler.NewContextJob(task, nil, scheduler.Every(time.Hour))
+	job.SetOverlapPolicy(scheduler.OverlapPolicy{Mode: scheduler.OverlapAllow, Limit: 2})
+	defer job.Stop()
+
+	job.RunNow()
+	job.RunNow()
+	job.RunNow()
+	waitFor(t, func() bool { return job.Running() == 2 })
+
+	job.Cancel()
+	waitFor(t, func() bool { return !job.InProgress() })
+	assert.Equals(t, 2, task.getCancelled())
+}
+
+func TestOverlap_Replace(t *testing.T) {
+	task := &blockingTask{}
+	job := scheduler.NewContextJob(task, nil, scheduler.Every(time.Hour))
+	job.SetOverlapPolicy(scheduler.OverlapPolicy{Mode: scheduler.OverlapReplace})
+	defer job.Stop()
+
+	job.RunNow()
+	waitFor(t, func() bool { return job.Running() == 1 })
+
+	// The second trigger cancels the running task and starts a fresh one.
+	job.RunNow()
+	waitFor(t, func() bool { return task.getCancelled() == 1 && job.Running() == 1 && !job.Pending() })
+	assert.Equals(t, 1, job.Stats().Runs)
+}
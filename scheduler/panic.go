@@ -0,0 +1,34 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error of runs whose task panicked.
+type PanicError struct {
+	// Value is the value the task panicked with.
+	Value interface{}
+	// Stack is the stack trace of the panicking go routine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// runTask runs the task once. If the task panics, the panic is recovered and
+// returned as a permanent PanicError, so that the run is recorded as failed
+// without being retried and the job keeps running.
+func (j *Job) runTask(ctx context.Context) (err error) {
+	defer func() {
+		if recover := recover(); recover != nil {
+			stack := debug.Stack()
+			j.error("JOB=%s Panic: %+v", j.Name(), recover)
+			j.error("%s", stack)
+			err = Permanent(&PanicError{Value: recover, Stack: stack})
+		}
+	}()
+	return j.task.Run(ctx)
+}
@@ -0,0 +1,42 @@
+package scheduler_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+type panickingTask struct{}
+
+func (task *panickingTask) Run() error {
+	panic("boom")
+}
+
+func (task *panickingTask) Name() string {
+	return "panickingTask"
+}
+
+func (task *panickingTask) ID() int64 {
+	return 0
+}
+
+func TestPanic(t *testing.T) {
+	job := scheduler.NewJob(&panickingTask{}, nil, time.Hour)
+	defer job.Stop()
+	job.SetRetryPolicy(&scheduler.RetryPolicy{MaxAttempts: 3})
+
+	// The panic fails the run without retries, the job keeps working.
+	for i := 1; i <= 2; i++ {
+		job.RunNow()
+		waitFor(t, func() bool { return !job.InProgress() && job.Stats().Failures == i })
+	}
+	last, _ := job.LastRecord()
+	var pe *scheduler.PanicError
+	assert.True(t, errors.As(last.Err, &pe), "run should have failed with a panic error")
+	assert.Equals(t, "boom", pe.Value)
+	assert.True(t, len(pe.Stack) > 0, "stack trace should be recorded")
+	assert.Equals(t, 1, last.Attempts)
+}
@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultPayloadQueueSize is the number of pending payloads a job accepts by default.
+const DefaultPayloadQueueSize = 100
+
+// PayloadTask interface describes a task whose runs receive a payload, e.g.
+// the entity to process. Runs which were not triggered by RunNowWith receive
+// a nil payload.
+type PayloadTask interface {
+	Run(ctx context.Context, payload interface{}) error
+	ID() int64
+	Name() string
+}
+
+// payloadKey is the context key of the payload of a run.
+type payloadKey struct{}
+
+// payloadTaskAdapter turns a PayloadTask into a ContextTask
+// which takes the payload from the context.
+type payloadTaskAdapter struct {
+	PayloadTask
+}
+
+func (t payloadTaskAdapter) Run(ctx context.Context) error {
+	return t.PayloadTask.Run(ctx, ctx.Value(payloadKey{}))
+}
+
+// NewPayload creates a new job for the given payload task, see New and RunNowWith.
+func NewPayload(task PayloadTask, opts ...Option) *Job {
+	return NewContext(payloadTaskAdapter{task}, opts...)
+}
+
+// RunNowWith queues a run of the job with the given payload and returns
+// without blocking. Queued payloads are run one after the other, in the
+// order they were queued, subject to the overlap policy but not to the
+// manual trigger policy or blackouts. Paused jobs keep the payloads until
+// they are resumed. It returns false if the queue is full or the job was stopped.
+func (j *Job) RunNowWith(payload interface{}) bool {
+	if j.stopping() {
+		return false
+	}
+	j.mutex.Lock()
+	if j.payloadQueueSize > 0 && len(j.payloads) >= j.payloadQueueSize {
+		j.mutex.Unlock()
+		return false
+	}
+	j.payloads = append(j.payloads, payload)
+	j.mutex.Unlock()
+
+	j.payloadQueued()
+	return true
+}
+
+// SetPayloadQueueSize sets the number of pending payloads the job accepts,
+// zero means no limit. Payloads which are already queued are kept.
+func (j *Job) SetPayloadQueueSize(n int) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.payloadQueueSize = n
+}
+
+// PendingPayloads returns the queued payloads which were not run yet, oldest first.
+func (j *Job) PendingPayloads() []interface{} {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return append([]interface{}(nil), j.payloads...)
+}
+
+// payloadQueued makes the job's go routine pick up the queued payloads.
+func (j *Job) payloadQueued() {
+	select {
+	case j.runPayload <- struct{}{}:
+	default:
+	}
+}
+
+// nextPayload removes the oldest payload from the queue, the mutex must be held.
+func (j *Job) nextPayload() interface{} {
+	if len(j.payloads) == 0 {
+		return nil
+	}
+	payload := j.payloads[0]
+	j.payloads[0] = nil
+	j.payloads = j.payloads[1:]
+	return payload
+}
+
+// TypedPayloadTask interface describes a task whose runs receive a payload
+// of type T. Runs which were not triggered by RunNowWith receive the zero value.
+type TypedPayloadTask[T any] interface {
+	Run(ctx context.Context, payload T) error
+	ID() int64
+	Name() string
+}
+
+// typedPayloadTaskAdapter turns a TypedPayloadTask into a PayloadTask.
+type typedPayloadTaskAdapter[T any] struct {
+	TypedPayloadTask[T]
+}
+
+func (t typedPayloadTaskAdapter[T]) Run(ctx context.Context, payload interface{}) error {
+	var typed T
+	if payload != nil {
+		var ok bool
+		if typed, ok = payload.(T); !ok {
+			return Permanent(fmt.Errorf("could not run task: unexpected payload of type %T", payload))
+		}
+	}
+	return t.TypedPayloadTask.Run(ctx, typed)
+}
+
+// TypedJob is a job whose runs receive payloads of type T. Its embedded Job
+// is what gets added to a service.
+type TypedJob[T any] struct {
+	*Job
+}
+
+// NewTypedPayload creates a new job for the given typed payload task, see NewPayload.
+func NewTypedPayload[T any](task TypedPayloadTask[T], opts ...Option) *TypedJob[T] {
+	return &TypedJob[T]{NewPayload(typedPayloadTaskAdapter[T]{task}, opts...)}
+}
+
+// RunNowWith queues a run of the job with the given payload, see Job.RunNowWith.
+func (j *TypedJob[T]) RunNowWith(payload T) bool {
+	return j.Job.RunNowWith(payload)
+}
+
+// PendingPayloads returns the queued payloads which were not run yet, oldest first.
+func (j *TypedJob[T]) PendingPayloads() []T {
+	var payloads []T
+	for _, payload := range j.Job.PendingPayloads() {
+		if typed, ok := payload.(T); ok {
+			payloads = append(payloads, typed)
+		}
+	}
+	return payloads
+}
@@ -0,0 +1,122 @@
+package scheduler_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+type payloadTask struct {
+	mutex    sync.Mutex
+	payloads []interface{}
+}
+
+func (task *payloadTask) getPayloads() []interface{} {
+	task.mutex.Lock()
+	defer task.mutex.Unlock()
+	return append([]interface{}(nil), task.payloads...)
+}
+
+func (task *payloadTask) Run(ctx context.Context, payload interface{}) error {
+	task.mutex.Lock()
+	defer task.mutex.Unlock()
+	task.payloads = append(task.payloads, payload)
+	return nil
+}
+
+func (task *payloadTask) Name() string {
+	return "payloadTask"
+}
+
+func (task *payloadTask) ID() int64 {
+	return 0
+}
+
+type user struct {
+	Name string
+}
+
+type typedPayloadTask struct {
+	mutex sync.Mutex
+	users []user
+}
+
+func (task *typedPayloadTask) getUsers() []user {
+	task.mutex.Lock()
+	defer task.mutex.Unlock()
+	return append([]user(nil), task.users...)
+}
+
+func (task *typedPayloadTask) Run(ctx context.Context, payload user) error {
+	task.mutex.Lock()
+	defer task.mutex.Unlock()
+	task.users = append(task.users, payload)
+	return nil
+}
+
+func (task *typedPayloadTask) Name() string {
+	return "typedPayloadTask"
+}
+
+func (task *typedPayloadTask) ID() int64 {
+	return 0
+}
+
+func TestRunNowWith(t *testing.T) {
+	task := &payloadTask{}
+	job := scheduler.NewPayload(task, scheduler.WithInterval(time.Hour))
+	defer job.Stop()
+
+	// All payloads are run in order, even if triggered while running.
+	for _, user := range []string{"alice", "bob", "carol"} {
+		assert.True(t, job.RunNowWith(user), "payload should be queued")
+	}
+	waitFor(t, func() bool { return len(task.getPayloads()) == 3 })
+	assert.Equals(t, []interface{}{"alice", "bob", "carol"}, task.getPayloads())
+
+	last, _ := job.LastRecord()
+	assert.Equals(t, "carol", last.Payload)
+	assert.Equals(t, scheduler.PayloadTrigger, last.Trigger)
+}
+
+func TestRunNowWith_Paused(t *testing.T) {
+	task := &payloadTask{}
+	job := scheduler.NewPayload(task, scheduler.StartPaused(), scheduler.WithPayloadQueueSize(1))
+	defer job.Stop()
+
+	assert.True(t, job.RunNowWith(1), "payload should be queued")
+	assert.True(t, !job.RunNowWith(2), "queue should be full")
+	time.Sleep(time.Millisecond * 10)
+	assert.Equals(t, []interface{}{1}, job.PendingPayloads())
+
+	job.Resume()
+	waitFor(t, func() bool { return len(task.getPayloads()) == 1 })
+	assert.Equals(t, 0, len(job.PendingPayloads()))
+}
+
+func TestTypedPayload(t *testing.T) {
+	task := &typedPayloadTask{}
+	job := scheduler.NewTypedPayload[user](task, scheduler.StartPaused())
+	defer job.Stop()
+
+	// Payloads keep their type, runs without one receive the zero value.
+	assert.True(t, job.RunNowWith(user{"alice"}), "payload should be queued")
+	time.Sleep(time.Millisecond * 10)
+	assert.Equals(t, []user{{"alice"}}, job.PendingPayloads())
+
+	job.Resume()
+	waitFor(t, func() bool { return len(task.getUsers()) == 1 })
+	job.RunNow()
+	waitFor(t, func() bool { return len(task.getUsers()) == 2 })
+	assert.Equals(t, []user{{"alice"}, {}}, task.getUsers())
+
+	// Payloads of another type fail the run.
+	assert.True(t, job.Job.RunNowWith(42), "payload should be queued")
+	waitFor(t, func() bool { return job.Stats().Runs == 3 })
+	last, _ := job.LastRecord()
+	assert.Error(t, last.Err)
+}
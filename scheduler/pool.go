@@ -0,0 +1,280 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Pool limits how many tasks run concurrently across all jobs of a service.
+// Runs which exceed the limit are queued, higher priorities first and in
+// order of arrival within the same priority. Groups of jobs may be limited
+// further, a group at its limit doesn't block runs of other groups.
+type Pool struct {
+	mutex        sync.Mutex
+	limit        int
+	groupLimits  map[string]int
+	running      int
+	groupRunning map[string]int
+	queue        []*poolWaiter
+	seq          uint64
+
+	// Statistics about the time runs spent in the queue.
+	waits     int
+	totalWait time.Duration
+	maxWait   time.Duration
+}
+
+// PoolStats contains statistics about a pool.
+type PoolStats struct {
+	Limit    int
+	Running  int
+	Queued   int
+	MeanWait time.Duration
+	MaxWait  time.Duration
+}
+
+type poolWaiter struct {
+	group    string
+	priority int
+	seq      uint64
+	ready    chan struct{}
+}
+
+// NewPool constructs a new pool which runs at most limit tasks at once.
+// Limits below one are raised to one, a pool always runs some task.
+func NewPool(limit int) *Pool {
+	if limit < 1 {
+		limit = 1
+	}
+	return &Pool{
+		limit:        limit,
+		groupLimits:  make(map[string]int),
+		groupRunning: make(map[string]int),
+	}
+}
+
+// SetGroupLimit limits the number of concurrently running tasks of
+// the given group, a limit of zero removes the group limit.
+func (p *Pool) SetGroupLimit(group string, limit int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if limit > 0 {
+		p.groupLimits[group] = limit
+	} else {
+		delete(p.groupLimits, group)
+	}
+	p.dispatch()
+}
+
+// QueueLength returns the number of runs waiting for a free slot.
+func (p *Pool) QueueLength() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.queue)
+}
+
+// Running returns the number of currently running tasks.
+func (p *Pool) Running() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.running
+}
+
+// Stats returns statistics about the pool.
+func (p *Pool) Stats() PoolStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	stats := PoolStats{
+		Limit:   p.limit,
+		Running: p.running,
+		Queued:  len(p.queue),
+		MaxWait: p.maxWait,
+	}
+	if p.waits > 0 {
+		stats.MeanWait = p.totalWait / time.Duration(p.waits)
+	}
+	return stats
+}
+
+// acquire waits for a free slot and returns how long it waited.
+func (p *Pool) acquire(ctx context.Context, group string, priority int) (time.Duration, error) {
+	start := time.Now()
+	p.mutex.Lock()
+	p.seq++
+	w := &poolWaiter{
+		group:    group,
+		priority: priority,
+		seq:      p.seq,
+		ready:    make(chan struct{}),
+	}
+	p.queue = append(p.queue, w)
+	sort.SliceStable(p.queue, func(a, b int) bool {
+		if p.queue[a].priority != p.queue[b].priority {
+			return p.queue[a].priority > p.queue[b].priority
+		}
+		return p.queue[a].seq < p.queue[b].seq
+	})
+	p.dispatch()
+	p.mutex.Unlock()
+
+	select {
+	case <-w.ready:
+		waited := time.Since(start)
+		p.mutex.Lock()
+		p.waits++
+		p.totalWait += waited
+		if waited > p.maxWait {
+			p.maxWait = waited
+		}
+		p.mutex.Unlock()
+		return waited, nil
+	case <-ctx.Done():
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		for i, queued := range p.queue {
+			if queued == w {
+				p.queue = append(p.queue[:i], p.queue[i+1:]...)
+				return time.Since(start), ctx.Err()
+			}
+		}
+		// The slot was granted concurrently, give it back.
+		p.releaseLocked(group)
+		return time.Since(start), ctx.Err()
+	}
+}
+
+// release frees the slot of a finished task.
+func (p *Pool) release(group string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.releaseLocked(group)
+}
+
+func (p *Pool) releaseLocked(group string) {
+	p.running--
+	p.groupRunning[group]--
+	p.dispatch()
+}
+
+// dispatch grants free slots to queued runs, the mutex must be held.
+func (p *Pool) dispatch() {
+	for i := 0; i < len(p.queue) && p.running < p.limit; {
+		w := p.queue[i]
+		if limit, ok := p.groupLimits[w.group]; ok && p.groupRunning[w.group] >= limit {
+			i++
+			continue
+		}
+		p.queue = append(p.queue[:i], p.queue[i+1:]...)
+		p.running++
+		p.groupRunning[w.group]++
+		close(w.ready)
+	}
+}
+
+// errStopping is returned by waitForPool if the job stopped while waiting.
+var errStopping = errors.New("job is stopping")
+
+// SetGroup sets the pool group of the job.
+func (j *Job) SetGroup(group string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.group = group
+}
+
+// Group returns the pool group of the job.
+func (j *Job) Group() string {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.group
+}
+
+// SetPriority sets the priority of the job's runs when waiting for the pool.
+func (j *Job) SetPriority(priority int) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.priority = priority
+}
+
+// Priority returns the priority of the job's runs.
+func (j *Job) Priority() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.priority
+}
+
+// Queued returns whether a run of the job is waiting for the pool.
+func (j *Job) Queued() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.queued
+}
+
+func (j *Job) setPool(p *Pool) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.pool = p
+}
+
+// waitForPool waits for a slot in the job's pool, if it has one. The returned
+// function must be called to free the slot once the task finished. It returns
+// errStopping if the job stops while waiting.
+func (j *Job) waitForPool(ctx context.Context) (time.Duration, func(), error) {
+	j.mutex.Lock()
+	pool, group, priority := j.pool, j.group, j.priority
+	if pool != nil {
+		j.queued = true
+	}
+	j.mutex.Unlock()
+
+	if pool == nil {
+		return 0, func() {}, nil
+	}
+
+	// Give up waiting once the job stops, queued runs are dropped then.
+	waitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-j.stop:
+			cancel()
+		case <-waitCtx.Done():
+		}
+	}()
+	waited, err := pool.acquire(waitCtx, group, priority)
+	j.mutex.Lock()
+	j.queued = false
+	j.mutex.Unlock()
+	if err != nil && j.stopping() {
+		return waited, nil, errStopping
+	} else if err != nil {
+		return waited, nil, err
+	}
+	if j.stopping() {
+		pool.release(group)
+		return waited, nil, errStopping
+	}
+	if waited > time.Millisecond {
+		j.info("JOB=%s Waited %s for a free worker.", j.Name(), waited)
+	}
+	return waited, func() { pool.release(group) }, nil
+}
+
+// SetPool makes all jobs of the service share the given pool, nil removes it.
+func (s *Service) SetPool(p *Pool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.pool = p
+	for _, j := range s.jobs {
+		j.setPool(p)
+	}
+}
+
+// Pool returns the pool shared by the jobs of the service.
+func (s *Service) Pool() *Pool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.pool
+}
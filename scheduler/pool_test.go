@@ -0,0 +1,117 @@
+package scheduler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+func TestPool(t *testing.T) {
+	first, second := &blockingTask{}, &blockingTask{}
+	firstJob := scheduler.NewContextJob(first, nil, scheduler.Every(time.Hour))
+	secondJob := scheduler.NewContextJob(second, nil, scheduler.Every(time.Hour))
+
+	s := scheduler.NewService()
+	s.AddJob(firstJob)
+	s.AddJob(secondJob)
+	pool := scheduler.NewPool(1)
+	s.SetPool(pool)
+	defer s.StopAll(0)
+
+	firstJob.RunNow()
+	time.Sleep(time.Millisecond * 15)
+	secondJob.RunNow()
+	time.Sleep(time.Millisecond * 15)
+
+	// The second run has to wait for the first one.
+	assert.Equals(t, 1, pool.Running())
+	assert.Equals(t, 1, pool.QueueLength())
+	assert.True(t, secondJob.Queued(), "second job should be queued")
+
+	firstJob.Cancel()
+	time.Sleep(time.Millisecond * 15)
+
+	assert.Equals(t, 1, pool.Running())
+	assert.Equals(t, 0, pool.QueueLength())
+	assert.True(t, !secondJob.Queued(), "second job should not be queued anymore")
+	assert.True(t, pool.Stats().MaxWait >= time.Millisecond*15, "wait time should be recorded")
+
+	secondJob.Cancel()
+	time.Sleep(time.Millisecond * 15)
+	last, ok := secondJob.LastRecord()
+	assert.True(t, ok, "second job should have finished")
+	assert.True(t, last.Wait >= time.Millisecond*15, "wait time should be part of the record")
+}
+
+func TestPool_Groups(t *testing.T) {
+	a, b, c := &blockingTask{}, &blockingTask{}, &blockingTask{}
+	aJob := scheduler.NewContextJob(a, nil, scheduler.Every(time.Hour))
+	bJob := scheduler.NewContextJob(b, nil, scheduler.Every(time.Hour))
+	cJob := scheduler.NewContextJob(c, nil, scheduler.Every(time.Hour))
+	aJob.SetGroup("db")
+	bJob.SetGroup("db")
+
+	s := scheduler.NewService()
+	pool := scheduler.NewPool(2)
+	pool.SetGroupLimit("db", 1)
+	s.SetPool(pool)
+	s.AddJob(aJob)
+	s.AddJob(bJob)
+	s.AddJob(cJob)
+	defer s.StopAll(0)
+
+	aJob.RunNow()
+	bJob.RunNow()
+	cJob.RunNow()
+	time.Sleep(time.Millisecond * 15)
+
+	// The database group is at its limit, the third job may still run.
+	assert.Equals(t, 2, pool.Running())
+	assert.True(t, aJob.Queued() != bJob.Queued(), "one db job should be queued")
+	assert.True(t, !cJob.Queued(), "other job should not be queued")
+}
+
+func TestPool_InvalidLimit(t *testing.T) {
+	task := &testTask{}
+	job := scheduler.NewJob(task, nil, time.Hour)
+	s := scheduler.NewService()
+	s.AddJob(job)
+	pool := scheduler.NewPool(0)
+	s.SetPool(pool)
+	defer s.StopAll(0)
+
+	// A pool without a positive limit still runs one task at a time.
+	assert.Equals(t, 1, pool.Stats().Limit)
+	job.RunNow()
+	waitFor(t, func() bool { return task.getCount() == 1 })
+}
+
+func TestPool_Shutdown(t *testing.T) {
+	task := &testTask{}
+	blockingJob := scheduler.NewContextJob(&blockingTask{}, nil, scheduler.Every(time.Hour))
+	queuedJob := scheduler.NewJob(task, nil, time.Hour)
+
+	s := scheduler.NewService()
+	s.AddJob(blockingJob)
+	s.AddJob(queuedJob)
+	s.SetPool(scheduler.NewPool(1))
+
+	blockingJob.RunNow()
+	time.Sleep(time.Millisecond * 15)
+	queuedJob.RunNow()
+	time.Sleep(time.Millisecond * 15)
+	assert.True(t, queuedJob.Queued(), "job should be queued")
+
+	// Queued runs are dropped instead of running during the drain.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	report, err := s.Shutdown(ctx)
+	assert.Equals(t, context.DeadlineExceeded, err)
+	assert.Equals(t, []string{"blockingTask"}, report.Interrupted)
+	assert.Equals(t, 0, task.getCount())
+	_, ok := queuedJob.LastRecord()
+	assert.True(t, !ok, "dropped run should not be recorded")
+}
@@ -0,0 +1,215 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/imba3r/pkg/config"
+)
+
+// TaskFactory creates the task of a job defined in the config.
+type TaskFactory func() Task
+
+// Config is the config section defining the jobs of a service, e.g.
+//
+//	{"Jobs": [
+//		{"Task": "cleanup", "Interval": "1h"},
+//		{"Task": "report", "Schedule": "0 0 * * *", "Timeout": "5m", "Paused": true}
+//	]}
+type Config struct {
+	Jobs []JobDefinition
+}
+
+// JobDefinition defines a job in the config.
+type JobDefinition struct {
+	// Task is the name the task's factory was registered with.
+	Task string
+	// Interval is the interval of the job, e.g. "5m".
+	Interval string
+	// Schedule is a cron expression, see ParseCron. It is an alternative to
+	// Interval, jobs with neither of them only run when triggered.
+	Schedule string
+	// Timeout is the maximum duration of a single attempt, e.g. "30s".
+	Timeout string
+	// Paused defines whether the job is paused.
+	Paused bool
+}
+
+// schedule returns the schedule of the defined job.
+func (d JobDefinition) schedule() (Schedule, error) {
+	switch {
+	case d.Interval != "" && d.Schedule != "":
+		return nil, fmt.Errorf("job %q has both an interval and a schedule", d.Task)
+	case d.Interval != "":
+		interval, err := time.ParseDuration(d.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse interval of job %q: %v", d.Task, err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("interval of job %q must be positive", d.Task)
+		}
+		return Every(interval), nil
+	case d.Schedule != "":
+		return ParseCron(d.Schedule)
+	}
+	return manualSchedule{}, nil
+}
+
+// timeout returns the timeout of the defined job.
+func (d JobDefinition) timeout() (time.Duration, error) {
+	if d.Timeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(d.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse timeout of job %q: %v", d.Task, err)
+	}
+	return timeout, nil
+}
+
+// LoadConfig returns the config section with the given name from the config service.
+// After reloading the config from disk, it returns the reloaded section.
+func LoadConfig(cs *config.Service, section string) (Config, error) {
+	var sections map[string]json.RawMessage
+	if err := cs.LoadFromMemory(&sections); err != nil {
+		return Config{}, err
+	}
+	raw, ok := sections[section]
+	if !ok {
+		return Config{}, fmt.Errorf("could not find config section %q", section)
+	}
+	var cfg Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return Config{}, fmt.Errorf("could not unmarshal config section %q: %v", section, err)
+	}
+	return cfg, nil
+}
+
+// Registry maps task names to task factories. It builds a service from a
+// config and keeps the service in sync with the config when it is reloaded.
+type Registry struct {
+	logger Logger
+
+	mutex       sync.Mutex
+	factories   map[string]TaskFactory
+	service     *Service
+	jobs        map[string]*Job
+	definitions map[string]JobDefinition
+}
+
+// NewRegistry constructs a new registry. It accepts an optional logger
+// which is passed to all jobs, if that is nil the jobs will be quiet.
+func NewRegistry(logger Logger) *Registry {
+	return &Registry{
+		logger:      logger,
+		factories:   make(map[string]TaskFactory),
+		jobs:        make(map[string]*Job),
+		definitions: make(map[string]JobDefinition),
+	}
+}
+
+// Register registers the factory of the task with the given name.
+func (r *Registry) Register(name string, factory TaskFactory) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.factories[name] = factory
+}
+
+// Build builds a new service with the jobs defined in the given config.
+// A registry builds a single service, which it keeps in sync on Reload.
+func (r *Registry) Build(cfg Config) (*Service, error) {
+	r.mutex.Lock()
+	if r.service != nil {
+		r.mutex.Unlock()
+		return nil, fmt.Errorf("could not build service: registry built one already")
+	}
+	service := NewService()
+	r.service = service
+	r.mutex.Unlock()
+
+	if err := r.Reload(cfg); err != nil {
+		r.mutex.Lock()
+		r.service = nil
+		r.mutex.Unlock()
+		return nil, err
+	}
+	return service, nil
+}
+
+// Reload applies the given config to the service built before. Jobs whose
+// interval, schedule, timeout or paused state changed are updated, new jobs
+// are added and jobs which are not defined anymore are removed. If the config
+// is invalid, an error is returned and the service is left untouched.
+func (r *Registry) Reload(cfg Config) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.service == nil {
+		return fmt.Errorf("could not reload config: registry has no service, build it first")
+	}
+
+	// Validate the whole config before changing anything.
+	schedules := make(map[string]Schedule)
+	timeouts := make(map[string]time.Duration)
+	for _, d := range cfg.Jobs {
+		if _, ok := r.factories[d.Task]; !ok {
+			return fmt.Errorf("could not reload config: no task registered as %q", d.Task)
+		}
+		if _, ok := schedules[d.Task]; ok {
+			return fmt.Errorf("could not reload config: job %q is defined twice", d.Task)
+		}
+		schedule, err := d.schedule()
+		if err != nil {
+			return fmt.Errorf("could not reload config: %v", err)
+		}
+		timeout, err := d.timeout()
+		if err != nil {
+			return fmt.Errorf("could not reload config: %v", err)
+		}
+		schedules[d.Task], timeouts[d.Task] = schedule, timeout
+	}
+
+	for _, d := range cfg.Jobs {
+		job, ok := r.jobs[d.Task]
+		if !ok {
+			opts := []Option{WithLogger(r.logger), WithSchedule(schedules[d.Task]), WithTimeout(timeouts[d.Task])}
+			if d.Paused {
+				opts = append(opts, StartPaused())
+			}
+			job = New(r.factories[d.Task](), opts...)
+			r.service.AddJob(job)
+			r.jobs[d.Task] = job
+			r.definitions[d.Task] = d
+			continue
+		}
+
+		// Only apply what changed, so that e.g. pausing a job at runtime
+		// survives unrelated changes to the config.
+		prev := r.definitions[d.Task]
+		if d.Interval != prev.Interval || d.Schedule != prev.Schedule {
+			job.UpdateSchedule(schedules[d.Task])
+		}
+		if d.Timeout != prev.Timeout {
+			job.SetTimeout(timeouts[d.Task])
+		}
+		if d.Paused != prev.Paused {
+			if d.Paused {
+				job.Pause()
+			} else {
+				job.Resume()
+			}
+		}
+		r.definitions[d.Task] = d
+	}
+
+	for name, job := range r.jobs {
+		if _, ok := schedules[name]; !ok {
+			r.service.RemoveJob(job)
+			delete(r.jobs, name)
+			delete(r.definitions, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package scheduler_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/config"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+type appConfig struct {
+	Name      string
+	Scheduler scheduler.Config
+}
+
+func TestRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scheduler-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := appConfig{Name: "app", Scheduler: scheduler.Config{Jobs: []scheduler.JobDefinition{
+		{Task: "testTask", Interval: "1h"},
+		{Task: "failingTask", Schedule: "0 0 * * *", Paused: true},
+	}}}
+	cs, err := config.NewService(filepath.Join(dir, "app.cfg"), &cfg)
+	assert.NoError(t, err)
+
+	r := scheduler.NewRegistry(nil)
+	r.Register("testTask", func() scheduler.Task { return &testTask{} })
+	r.Register("failingTask", func() scheduler.Task { return &failingTask{} })
+
+	section, err := scheduler.LoadConfig(cs, "Scheduler")
+	assert.NoError(t, err)
+	s, err := r.Build(section)
+	assert.NoError(t, err)
+	defer s.StopAll(0)
+
+	_, err = r.Build(section)
+	assert.Error(t, err)
+
+	hourly, ok := s.JobByName("testTask")
+	assert.True(t, ok, "testTask job should exist")
+	assert.Equals(t, time.Hour, hourly.CurrentInterval())
+	daily, ok := s.JobByName("failingTask")
+	assert.True(t, ok, "failingTask job should exist")
+	assert.Equals(t, scheduler.Disabled, daily.State())
+
+	// Change the config on disk and reload it.
+	cfg.Scheduler.Jobs = []scheduler.JobDefinition{{Task: "testTask", Interval: "30m", Paused: true}}
+	assert.NoError(t, cs.Save(&cfg))
+	assert.NoError(t, cs.LoadFromDisk(&cfg))
+	section, err = scheduler.LoadConfig(cs, "Scheduler")
+	assert.NoError(t, err)
+	assert.NoError(t, r.Reload(section))
+
+	waitFor(t, func() bool { return hourly.CurrentInterval() == time.Minute*30 })
+	assert.Equals(t, scheduler.Disabled, hourly.State())
+	_, ok = s.JobByName("failingTask")
+	assert.True(t, !ok, "failingTask job should be removed")
+}
+
+func TestRegistry_Invalid(t *testing.T) {
+	r := scheduler.NewRegistry(nil)
+	r.Register("testTask", func() scheduler.Task { return &testTask{} })
+
+	for _, d := range []scheduler.JobDefinition{
+		{Task: "unknown", Interval: "1h"},
+		{Task: "testTask", Interval: "often"},
+		{Task: "testTask", Interval: "1h", Schedule: "@daily"},
+		{Task: "testTask", Schedule: "* * *"},
+		{Task: "testTask", Timeout: "-"},
+	} {
+		_, err := r.Build(scheduler.Config{Jobs: []scheduler.JobDefinition{d}})
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,182 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy describes how failed runs of a job are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts per run, including the first one.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts, zero means no cap.
+	MaxBackoff time.Duration
+	// Multiplier is applied to the backoff after every retry, defaults to 2.
+	Multiplier float64
+	// Jitter randomizes every backoff by up to the given fraction, e.g. 0.2 for +/-20%.
+	Jitter float64
+	// Retryable decides whether an error is worth retrying. If it is nil all
+	// errors are retried except the ones marked with Permanent.
+	Retryable func(err error) bool
+}
+
+// backoff returns the delay before the given retry, starting with 1.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		backoff *= multiplier
+		if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff += backoff * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(backoff)
+}
+
+// shouldRetry returns whether another attempt should follow the given failed one.
+func (p *RetryPolicy) shouldRetry(attempt int, err error) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return !IsPermanent(err)
+}
+
+// permanentError marks an error as not retryable.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks the given error as permanent, failed runs
+// returning it are not retried by the default retry policy.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// IsPermanent returns whether the given error was marked as permanent.
+func IsPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+// SetRetryPolicy sets the retry policy of the job, nil disables retries.
+func (j *Job) SetRetryPolicy(p *RetryPolicy) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if p != nil {
+		copied := *p
+		p = &copied
+	}
+	j.retry = p
+}
+
+// RetryPolicy returns the retry policy of the job, nil if there is none.
+func (j *Job) RetryPolicy() *RetryPolicy {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.retry == nil {
+		return nil
+	}
+	copied := *j.retry
+	return &copied
+}
+
+// Attempt returns the attempt of the currently running task, starting
+// with 1. If several runs overlap it is the highest attempt among them.
+// It is zero if the task is not in progress.
+func (j *Job) Attempt() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	attempt := 0
+	for _, a := range j.attempts {
+		if a > attempt {
+			attempt = a
+		}
+	}
+	return attempt
+}
+
+// LastAttempts returns how many attempts the last finished run took.
+func (j *Job) LastAttempts() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.lastAttempts
+}
+
+// execute runs the task until it succeeds, the retry policy gives up or
+// the given context is cancelled. Every attempt is subject to the timeout.
+// It returns the number of attempts of the run with the given id.
+func (j *Job) execute(ctx context.Context, id int) (int, error) {
+	j.mutex.Lock()
+	policy := j.retry
+	timeout := j.timeout
+	j.mutex.Unlock()
+
+	defer func() {
+		j.mutex.Lock()
+		delete(j.attempts, id)
+		j.mutex.Unlock()
+	}()
+	for attempt := 1; ; attempt++ {
+		j.mutex.Lock()
+		j.attempts[id] = attempt
+		j.mutex.Unlock()
+
+		err := j.attemptOnce(ctx, timeout)
+		if err == nil || ctx.Err() != nil || policy == nil || !policy.shouldRetry(attempt, err) {
+			return attempt, err
+		}
+
+		backoff := policy.backoff(attempt)
+		j.error("JOB=%s Attempt %d failed: %v, retrying in %s.", j.Name(), attempt, err, backoff)
+		timer := j.clock.NewTimer(backoff)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			// Don't start another attempt, tasks without
+			// context support would run once more in full.
+			timer.Stop()
+			return attempt, ctx.Err()
+		}
+	}
+}
+
+// attemptOnce runs the task once with the given timeout.
+func (j *Job) attemptOnce(ctx context.Context, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	err := j.runTask(ctx)
+	if ctx.Err() == context.DeadlineExceeded {
+		j.error("JOB=%s Task timed out after %s.", j.Name(), timeout)
+	}
+	return err
+}
@@ -0,0 +1,155 @@
+package scheduler_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+type failingTask struct {
+	countMutex sync.Mutex
+	count      int
+	failures   int
+	err        error
+}
+
+func (task *failingTask) getCount() int {
+	task.countMutex.Lock()
+	defer task.countMutex.Unlock()
+	return task.count
+}
+
+func (task *failingTask) Run() error {
+	task.countMutex.Lock()
+	defer task.countMutex.Unlock()
+	task.count = task.count + 1
+	if task.count <= task.failures {
+		return task.err
+	}
+	return nil
+}
+
+func (task *failingTask) Name() string {
+	return "failingTask"
+}
+
+func (task *failingTask) ID() int64 {
+	return 0
+}
+
+func TestRetry(t *testing.T) {
+	task := &failingTask{failures: 2, err: errors.New("temporary failure")}
+
+	job := scheduler.NewJob(task, nil, time.Hour)
+	job.SetRetryPolicy(&scheduler.RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Millisecond,
+		Jitter:         0.5,
+	})
+	job.RunNow()
+	time.Sleep(time.Millisecond * 50)
+	job.Stop()
+
+	assert.Equals(t, 3, task.getCount())
+	assert.Equals(t, 3, job.LastAttempts())
+	assert.Equals(t, 0, job.Attempt())
+}
+
+func TestRetry_MaxAttempts(t *testing.T) {
+	task := &failingTask{failures: 10, err: errors.New("temporary failure")}
+
+	job := scheduler.NewJob(task, nil, time.Hour)
+	job.SetRetryPolicy(&scheduler.RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond})
+	job.RunNow()
+	time.Sleep(time.Millisecond * 50)
+	job.Stop()
+
+	assert.Equals(t, 4, task.getCount())
+}
+
+func TestRetry_Permanent(t *testing.T) {
+	task := &failingTask{failures: 10, err: scheduler.Permanent(errors.New("bad input"))}
+
+	job := scheduler.NewJob(task, nil, time.Hour)
+	job.SetRetryPolicy(&scheduler.RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond})
+	job.RunNow()
+	time.Sleep(time.Millisecond * 50)
+	job.Stop()
+
+	assert.Equals(t, 1, task.getCount())
+	assert.True(t, scheduler.IsPermanent(task.err), "error should be permanent")
+}
+
+func TestRetry_CancelDuringBackoff(t *testing.T) {
+	task := &failingTask{failures: 10, err: errors.New("temporary failure")}
+
+	job := scheduler.NewJob(task, nil, time.Hour)
+	job.SetRetryPolicy(&scheduler.RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Hour})
+	job.RunNow()
+	waitFor(t, func() bool { return task.getCount() == 1 })
+
+	// Cancelling ends the run, no further attempt is started.
+	job.Cancel()
+	waitFor(t, func() bool { return !job.InProgress() })
+	job.Stop()
+
+	assert.Equals(t, 1, task.getCount())
+	assert.Equals(t, 1, job.LastAttempts())
+}
+
+// overlappingTask blocks on its first call and fails on all further ones.
+type overlappingTask struct {
+	mutex   sync.Mutex
+	count   int
+	release chan struct{}
+}
+
+func (task *overlappingTask) Run() error {
+	task.mutex.Lock()
+	task.count++
+	first := task.count == 1
+	task.mutex.Unlock()
+	if first {
+		<-task.release
+		return nil
+	}
+	return errors.New("temporary failure")
+}
+
+func (task *overlappingTask) Name() string {
+	return "overlappingTask"
+}
+
+func (task *overlappingTask) ID() int64 {
+	return 0
+}
+
+func TestRetry_Overlapping(t *testing.T) {
+	task := &overlappingTask{release: make(chan struct{})}
+
+	job := scheduler.NewJob(task, nil, time.Hour)
+	job.SetOverlapPolicy(scheduler.OverlapPolicy{Mode: scheduler.OverlapAllow, Limit: 2})
+	job.SetRetryPolicy(&scheduler.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	defer job.Stop()
+
+	// Every run keeps its own attempts, the second one finishes first.
+	job.RunNow()
+	waitFor(t, func() bool { return job.Attempt() == 1 })
+	job.RunNow()
+	waitFor(t, func() bool { return job.LastAttempts() == 3 })
+	assert.Equals(t, 1, job.Running())
+	assert.Equals(t, 1, job.Attempt())
+
+	close(task.release)
+	waitFor(t, func() bool { return !job.InProgress() })
+	history := job.History()
+	assert.Equals(t, 2, len(history))
+	assert.Equals(t, 3, history[0].Attempts)
+	assert.Equals(t, 1, history[1].Attempts)
+	assert.Equals(t, 1, job.LastAttempts())
+	assert.Equals(t, 0, job.Attempt())
+}
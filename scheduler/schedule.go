@@ -0,0 +1,496 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule interface describes when a job runs.
+type Schedule interface {
+	// Next returns the next activation time strictly after t.
+	// A zero time means the schedule will never fire again.
+	Next(t time.Time) time.Time
+}
+
+// firstRunner is implemented by schedules which decide on the first run of a
+// job themselves, instead of it being the next activation after the job started.
+type firstRunner interface {
+	firstRun(now time.Time) time.Time
+}
+
+// firstRun returns the first activation of the given schedule for a job starting now.
+func firstRun(s Schedule, now time.Time) time.Time {
+	if fr, ok := s.(firstRunner); ok {
+		return fr.firstRun(now)
+	}
+	return s.Next(now)
+}
+
+// intervalSchedule fires at a fixed interval.
+type intervalSchedule struct {
+	interval time.Duration
+	aligned  bool
+}
+
+// Every returns a schedule which fires every d.
+// It panics if d is not positive, like time.NewTicker.
+func Every(d time.Duration) Schedule {
+	if d <= 0 {
+		panic("non-positive interval for Every")
+	}
+	return &intervalSchedule{interval: d}
+}
+
+// EveryAligned returns a schedule which fires every d, aligned to the wall
+// clock: it fires at multiples of d since the zero time, e.g. on the full
+// minute for time.Minute or on the full hour (UTC) for time.Hour. Multiples
+// of a day are aligned to midnight in the job's location instead.
+// It panics if d is not positive.
+func EveryAligned(d time.Duration) Schedule {
+	if d <= 0 {
+		panic("non-positive interval for EveryAligned")
+	}
+	return &intervalSchedule{interval: d, aligned: true}
+}
+
+// Next returns t plus the interval, or the next aligned time.
+func (s *intervalSchedule) Next(t time.Time) time.Time {
+	if s.aligned && s.interval%(24*time.Hour) == 0 {
+		return nextAlignedDay(t, int64(s.interval/(24*time.Hour)))
+	} else if s.aligned {
+		return t.Truncate(s.interval).Add(s.interval)
+	}
+	return t.Add(s.interval)
+}
+
+// nextAlignedDay returns the first midnight in t's location after t whose
+// date is a multiple of the given number of days since the zero time.
+func nextAlignedDay(t time.Time, days int64) time.Time {
+	y, m, d := t.Date()
+	date := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	elapsed := (date.Unix() - time.Time{}.Unix()) / (24 * 60 * 60)
+	next := date.AddDate(0, 0, int(days-elapsed%days))
+	return wallTime(time.Date(next.Year(), next.Month(), next.Day(), 0, 0, 0, 0, t.Location()), 0, 0, 0)
+}
+
+// String returns a string representation of the schedule.
+func (s *intervalSchedule) String() string {
+	if s.aligned {
+		return "@every " + s.interval.String() + " aligned"
+	}
+	return "@every " + s.interval.String()
+}
+
+// startSchedule changes when a job with another schedule runs first, either
+// at a fixed time or relative to the time the job starts.
+type startSchedule struct {
+	Schedule
+	first    time.Time
+	delay    time.Duration
+	relative bool
+}
+
+// StartAt returns a schedule which fires at the given time first
+// and afterwards according to the given schedule.
+func StartAt(s Schedule, t time.Time) Schedule {
+	return &startSchedule{Schedule: s, first: t}
+}
+
+// StartAfter returns a schedule which fires after the given
+// initial delay first and afterwards according to the given schedule.
+// The delay counts from the time the job starts, according to its clock.
+func StartAfter(s Schedule, delay time.Duration) Schedule {
+	return &startSchedule{Schedule: s, delay: delay, relative: true}
+}
+
+// StartImmediately returns a schedule which fires as soon as the job
+// starts and afterwards according to the given schedule.
+func StartImmediately(s Schedule) Schedule {
+	return &startSchedule{Schedule: s, relative: true}
+}
+
+// Next returns the first run if t is before it, otherwise
+// the next activation of the underlying schedule.
+func (s *startSchedule) Next(t time.Time) time.Time {
+	if !s.relative && t.Before(s.first) {
+		return s.first
+	}
+	return s.Schedule.Next(t)
+}
+
+func (s *startSchedule) firstRun(now time.Time) time.Time {
+	if s.relative {
+		return now.Add(s.delay)
+	}
+	return s.Next(now)
+}
+
+// String returns a string representation of the schedule.
+func (s *startSchedule) String() string {
+	if s.relative && s.delay == 0 {
+		return fmt.Sprintf("%v starting immediately", s.Schedule)
+	} else if s.relative {
+		return fmt.Sprintf("%v starting after %s", s.Schedule, s.delay)
+	}
+	return fmt.Sprintf("%v starting at %s", s.Schedule, s.first.Format(time.RFC3339))
+}
+
+// onceSchedule fires once at a fixed time or after a delay.
+type onceSchedule struct {
+	at       time.Time
+	delay    time.Duration
+	relative bool
+}
+
+// At returns a schedule which fires once at the given time. Jobs with such a
+// schedule fire right away if the time already passed when they're created.
+func At(t time.Time) Schedule {
+	return &onceSchedule{at: t}
+}
+
+// After returns a schedule which fires once after the given delay. The delay
+// counts from the time the job starts, according to its clock.
+func After(delay time.Duration) Schedule {
+	return &onceSchedule{delay: delay, relative: true}
+}
+
+// Next returns the time of the schedule if it is after t. Delayed
+// schedules only fire once after the job started, see firstRun.
+func (s *onceSchedule) Next(t time.Time) time.Time {
+	if !s.relative && s.at.After(t) {
+		return s.at
+	}
+	return time.Time{}
+}
+
+// One-shot jobs fire even if their time passed already.
+func (s *onceSchedule) firstRun(now time.Time) time.Time {
+	if s.relative {
+		return now.Add(s.delay)
+	}
+	return s.at
+}
+
+// String returns a string representation of the schedule.
+func (s *onceSchedule) String() string {
+	if s.relative {
+		return "@after " + s.delay.String()
+	}
+	return "@at " + s.at.Format(time.RFC3339)
+}
+
+// manualSchedule never fires, jobs with it only run when triggered.
+type manualSchedule struct{}
+
+// Next always returns the zero time.
+func (manualSchedule) Next(t time.Time) time.Time {
+	return time.Time{}
+}
+
+// String returns a string representation of the schedule.
+func (manualSchedule) String() string {
+	return "@manual"
+}
+
+// cronSchedule fires according to a cron expression. Every field is
+// represented by a bit set, bit n being set means value n matches.
+type cronSchedule struct {
+	spec     string
+	location *time.Location
+	second   uint64
+	minute   uint64
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+
+	// Whether day of month or day of week were restricted, if both
+	// are restricted a day matches if either of them matches.
+	domStar bool
+	dowStar bool
+}
+
+type cronField struct {
+	name     string
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	secondField = cronField{"second", 0, 59, nil}
+	minuteField = cronField{"minute", 0, 59, nil}
+	hourField   = cronField{"hour", 0, 23, nil}
+	domField    = cronField{"day of month", 1, 31, nil}
+	monthField  = cronField{"month", 1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = cronField{"day of week", 0, 6, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+
+	cronDescriptors = map[string]string{
+		"@yearly":   "0 0 0 1 1 *",
+		"@annually": "0 0 0 1 1 *",
+		"@monthly":  "0 0 0 1 * *",
+		"@weekly":   "0 0 0 * * 0",
+		"@daily":    "0 0 0 * * *",
+		"@midnight": "0 0 0 * * *",
+		"@hourly":   "0 0 * * * *",
+	}
+)
+
+// ParseCron parses a cron expression and returns the according schedule.
+//
+// Supported are the classic 5 field syntax (minute, hour, day of month,
+// month, day of week), a 6 field syntax with a leading seconds field and
+// the descriptors @yearly, @annually, @monthly, @weekly, @daily, @midnight,
+// @hourly and "@every <duration>". Fields may contain '*', lists, ranges,
+// steps and (for month and day of week) three letter names; both 0 and 7
+// denote sunday.
+//
+// The expression is evaluated in the location of the job, unless it starts
+// with "CRON_TZ=<location> " (or "TZ=<location> "), e.g. "CRON_TZ=Europe/Berlin
+// 0 0 * * *". Across daylight saving time transitions, wall clock times which
+// are skipped fire once at the transition and wall clock times which occur
+// twice fire at their first occurrence only.
+func ParseCron(spec string) (Schedule, error) {
+	expr := strings.TrimSpace(spec)
+	var location *time.Location
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		i := strings.IndexAny(expr, " \t")
+		if i < 0 {
+			return nil, fmt.Errorf("could not parse cron expression %q: missing fields after time zone", spec)
+		}
+		name := expr[strings.Index(expr, "=")+1 : i]
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse cron expression %q: %v", spec, err)
+		}
+		location, expr = loc, strings.TrimSpace(expr[i:])
+	}
+	if strings.HasPrefix(expr, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(expr[len("@every "):]))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse cron expression %q: %v", spec, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("could not parse cron expression %q: interval must be positive", spec)
+		}
+		return Every(d), nil
+	}
+	if strings.HasPrefix(expr, "@") {
+		descriptor, ok := cronDescriptors[strings.ToLower(expr)]
+		if !ok {
+			return nil, fmt.Errorf("could not parse cron expression %q: unknown descriptor", spec)
+		}
+		expr = descriptor
+	}
+
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("could not parse cron expression %q: expected 5 or 6 fields, got %d", spec, len(fields))
+	}
+
+	s := &cronSchedule{
+		spec:     spec,
+		location: location,
+		domStar:  fields[3] == "*" || fields[3] == "?",
+		dowStar:  fields[5] == "*" || fields[5] == "?",
+	}
+	var err error
+	for _, f := range []struct {
+		dst   *uint64
+		expr  string
+		field cronField
+	}{
+		{&s.second, fields[0], secondField},
+		{&s.minute, fields[1], minuteField},
+		{&s.hour, fields[2], hourField},
+		{&s.dom, fields[3], domField},
+		{&s.month, fields[4], monthField},
+		{&s.dow, fields[5], dowField},
+	} {
+		*f.dst, err = parseCronField(f.expr, f.field)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse cron expression %q: %v", spec, err)
+		}
+	}
+	return s, nil
+}
+
+// MustParseCron is like ParseCron but panics if the expression is invalid.
+func MustParseCron(spec string) Schedule {
+	s, err := ParseCron(spec)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func parseCronField(expr string, field cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, step := part, uint(1)
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.ParseUint(part[i+1:], 10, 8)
+			if err != nil || n == 0 {
+				return 0, fmt.Errorf("invalid step in %s field: %q", field.name, part)
+			}
+			rangeExpr, step = part[:i], uint(n)
+		}
+
+		var from, to uint
+		switch {
+		case rangeExpr == "*" || rangeExpr == "?":
+			from, to = field.min, field.max
+		case strings.Contains(rangeExpr, "-"):
+			bounds := strings.SplitN(rangeExpr, "-", 2)
+			var err error
+			if from, err = parseCronValue(bounds[0], field); err != nil {
+				return 0, err
+			}
+			if to, err = parseCronValue(bounds[1], field); err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			if from, err = parseCronValue(rangeExpr, field); err != nil {
+				return 0, err
+			}
+			to = from
+			if step > 1 {
+				// "5/15" is shorthand for "5-max/15".
+				to = field.max
+			}
+		}
+
+		// Sunday may be written as 7, normalize it to 0.
+		if field.name == dowField.name && to == 7 {
+			bits |= 1
+			if from == 7 {
+				continue
+			}
+			to = 6
+		}
+		if from > to {
+			return 0, fmt.Errorf("invalid range in %s field: %q", field.name, part)
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(expr string, field cronField) (uint, error) {
+	if v, ok := field.names[strings.ToLower(expr)]; ok {
+		return v, nil
+	}
+	n, err := strconv.ParseUint(expr, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value in %s field: %q", field.name, expr)
+	}
+	max := field.max
+	if field.name == dowField.name {
+		max = 7
+	}
+	if uint(n) < field.min || uint(n) > max {
+		return 0, fmt.Errorf("value out of range in %s field: %d", field.name, n)
+	}
+	return uint(n), nil
+}
+
+// cronSearchYears limits how far Next looks ahead for expressions
+// which rarely or never match, like "0 0 30 2 *".
+const cronSearchYears = 5
+
+// Next returns the next matching time strictly after t, in t's location
+// or the location of the expression.
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	if s.location != nil {
+		t = t.In(s.location)
+	}
+	start := t.Truncate(time.Second).Add(time.Second)
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	end := day.AddDate(cronSearchYears, 0, 0)
+
+	for first := true; day.Before(end); day, first = day.AddDate(0, 0, 1), false {
+		if !s.matchDay(day) {
+			continue
+		}
+		fromHour, fromMinute, fromSecond := 0, 0, 0
+		if first {
+			fromHour, fromMinute, fromSecond = start.Clock()
+		}
+		for h := fromHour; h < 24; h++ {
+			if s.hour&(1<<uint(h)) == 0 {
+				continue
+			}
+			minMinute := 0
+			if h == fromHour {
+				minMinute = fromMinute
+			}
+			for m := minMinute; m < 60; m++ {
+				if s.minute&(1<<uint(m)) == 0 {
+					continue
+				}
+				minSecond := 0
+				if h == fromHour && m == fromMinute {
+					minSecond = fromSecond
+				}
+				for sec := minSecond; sec < 60; sec++ {
+					if s.second&(1<<uint(sec)) == 0 {
+						continue
+					}
+					next := wallTime(day, h, m, sec)
+					if next.After(t) {
+						return next
+					}
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// wallTime returns the first instant the given wall clock time occurs on the
+// given day, or the end of the transition if it is skipped on that day.
+func wallTime(day time.Time, hour, min, sec int) time.Time {
+	t := time.Date(day.Year(), day.Month(), day.Day(), hour, min, sec, 0, day.Location())
+	zoneStart, _ := t.ZoneBounds()
+	if h, m, s := t.Clock(); h != hour || m != min || s != sec {
+		// Skipped, time.Date moved the time past the transition.
+		return zoneStart
+	}
+	_, offset := t.Zone()
+	_, prevOffset := zoneStart.Add(-time.Second).Zone()
+	if repeated := time.Duration(prevOffset-offset) * time.Second; repeated > 0 && t.Sub(zoneStart) < repeated {
+		// Repeated, time.Date returned the second occurrence.
+		return t.Add(-repeated)
+	}
+	return t
+}
+
+func (s *cronSchedule) matchDay(day time.Time) bool {
+	if s.month&(1<<uint(day.Month())) == 0 {
+		return false
+	}
+	domMatch := s.dom&(1<<uint(day.Day())) != 0
+	dowMatch := s.dow&(1<<uint(day.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// String returns the cron expression of the schedule.
+func (s *cronSchedule) String() string {
+	return s.spec
+}
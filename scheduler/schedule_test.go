@@ -0,0 +1,114 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+func TestParseCron(t *testing.T) {
+	from := time.Date(2019, time.March, 15, 10, 20, 30, 0, time.UTC) // Friday.
+
+	for _, tc := range []struct {
+		spec string
+		exp  time.Time
+	}{
+		{"* * * * *", time.Date(2019, time.March, 15, 10, 21, 0, 0, time.UTC)},
+		{"*/15 * * * * *", time.Date(2019, time.March, 15, 10, 20, 45, 0, time.UTC)},
+		{"30 2 * * mon-fri", time.Date(2019, time.March, 18, 2, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2019, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2019, time.March, 17, 12, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2019, time.March, 22, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2019, time.March, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2019, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", time.Date(2019, time.March, 15, 10, 22, 0, 0, time.UTC)},
+	} {
+		s, err := scheduler.ParseCron(tc.spec)
+		assert.NoError(t, err)
+		assert.Equals(t, tc.exp, s.Next(from))
+	}
+}
+
+func TestParseCron_Never(t *testing.T) {
+	s, err := scheduler.ParseCron("0 0 30 2 *")
+	assert.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero(), "schedule should never fire")
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "@sometimes", "@every -1m", "CRON_TZ=Nowhere/Land * * * * *"} {
+		_, err := scheduler.ParseCron(spec)
+		assert.Error(t, err)
+	}
+}
+
+func TestEvery_Invalid(t *testing.T) {
+	for _, d := range []time.Duration{0, -time.Minute} {
+		func() {
+			defer func() {
+				assert.True(t, recover() != nil, "interval %s should panic", d)
+			}()
+			scheduler.Every(d)
+		}()
+	}
+}
+
+func TestEveryAligned(t *testing.T) {
+	from := time.Date(2019, time.March, 15, 10, 20, 30, 0, time.UTC)
+	s := scheduler.EveryAligned(time.Minute)
+	assert.Equals(t, time.Date(2019, time.March, 15, 10, 21, 0, 0, time.UTC), s.Next(from))
+	assert.Equals(t, time.Date(2019, time.March, 15, 10, 22, 0, 0, time.UTC), s.Next(s.Next(from)))
+}
+
+func TestEveryAligned_Location(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+
+	// Daily schedules fire at midnight in the given location, also across DST.
+	s := scheduler.EveryAligned(24 * time.Hour)
+	from := time.Date(2019, time.March, 30, 10, 0, 0, 0, berlin)
+	assert.Equals(t, time.Date(2019, time.March, 31, 0, 0, 0, 0, berlin), s.Next(from))
+	assert.Equals(t, time.Date(2019, time.April, 1, 0, 0, 0, 0, berlin), s.Next(s.Next(from)))
+
+	// Sub-day and UTC schedules keep aligning to multiples since the zero time.
+	from = time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC)
+	for _, d := range []time.Duration{time.Hour, 24 * time.Hour, 7 * 24 * time.Hour} {
+		assert.Equals(t, from.Truncate(d).Add(d), scheduler.EveryAligned(d).Next(from))
+	}
+}
+
+func TestStartAt(t *testing.T) {
+	first := time.Date(2019, time.March, 15, 12, 0, 0, 0, time.UTC)
+	s := scheduler.StartAt(scheduler.Every(time.Hour), first)
+	assert.Equals(t, first, s.Next(first.Add(-time.Minute)))
+	assert.Equals(t, first.Add(time.Hour), s.Next(first))
+}
+
+func TestParseCron_DST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		spec string
+		from time.Time
+		exp  time.Time
+	}{
+		// Local midnight, in the job's location and in the expression's.
+		{"0 0 * * *", time.Date(2019, time.March, 15, 10, 0, 0, 0, berlin), time.Date(2019, time.March, 16, 0, 0, 0, 0, berlin)},
+		{"CRON_TZ=Europe/Berlin 0 0 * * *", time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC), time.Date(2019, time.March, 16, 0, 0, 0, 0, berlin)},
+		// 02:30 is skipped on March 31, the run happens at the transition.
+		{"30 2 * * *", time.Date(2019, time.March, 31, 1, 0, 0, 0, berlin), time.Date(2019, time.March, 31, 3, 0, 0, 0, berlin)},
+		{"30 2 * * *", time.Date(2019, time.March, 31, 3, 0, 0, 0, berlin), time.Date(2019, time.April, 1, 2, 30, 0, 0, berlin)},
+		// 02:30 occurs twice on October 27, the run happens at the first occurrence.
+		{"30 2 * * *", time.Date(2019, time.October, 27, 1, 0, 0, 0, berlin), time.Date(2019, time.October, 27, 0, 30, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2019, time.October, 27, 0, 30, 0, 0, time.UTC).In(berlin), time.Date(2019, time.October, 28, 2, 30, 0, 0, berlin)},
+	} {
+		s, err := scheduler.ParseCron(tc.spec)
+		assert.NoError(t, err)
+		next := s.Next(tc.from)
+		assert.True(t, next.Equal(tc.exp), "%s: expected %s, got %s", tc.spec, tc.exp, next)
+	}
+}
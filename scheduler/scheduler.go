@@ -1,6 +1,9 @@
 package scheduler
 
 import (
+	"context"
+	"math/rand"
+	"strings"
 	"sync"
 	"time"
 )
@@ -12,6 +15,33 @@ type Task interface {
 	Name() string
 }
 
+// ContextTask interface describes a runnable task which can be cancelled.
+// The context is cancelled when the job stops, when the job's timeout is
+// exceeded or when Cancel() is called on the job.
+type ContextTask interface {
+	Run(ctx context.Context) error
+	ID() int64
+	Name() string
+}
+
+// taskAdapter turns a Task into a ContextTask which ignores the context.
+type taskAdapter struct {
+	Task
+}
+
+func (t taskAdapter) Run(ctx context.Context) error {
+	return t.Task.Run()
+}
+
+// contextTaskAdapter turns a ContextTask into a Task which runs with a background context.
+type contextTaskAdapter struct {
+	ContextTask
+}
+
+func (t contextTaskAdapter) Run() error {
+	return t.ContextTask.Run(context.Background())
+}
+
 // Logger interface describes the kind of logger we'd like to have.
 type Logger interface {
 	Infof(msgFormat string, args ...interface{})
@@ -28,49 +58,262 @@ type Job struct {
 	Logger
 
 	// Communication with the go routine.
-	interval chan time.Duration
+	schedule chan Schedule
 	stop     chan struct{}
-	runNow   chan struct{}
+	runNow   chan Trigger
+	// Buffered, a manual trigger sent by TryRunNow.
+	runNowAsync chan struct{}
+	// Buffered, signals payloads queued by RunNowWith.
+	runPayload chan struct{}
+	// Buffered, a trigger sent once all upstream jobs succeeded.
+	runDependency chan struct{}
+	restore       chan restoreRequest
+	// Buffered, asks the go routine to schedule the current slot again.
+	rescheduled chan struct{}
+
+	// The task which is actually executed.
+	task ContextTask
+
+	// Source of time, e.g. a FakeClock in tests.
+	clock Clock
+
+	// Location in which the job's schedule is evaluated.
+	location *time.Location
+
+	// Context of the job, cancelled when the job stops.
+	ctx       context.Context
+	cancelAll context.CancelFunc
 
 	// Job meta data guarded by mutex.
-	mutex       sync.Mutex
-	nextRun     time.Time
-	lastRun     time.Time
-	curInterval time.Duration
-	inProgress  bool
-	state       State
-
-	// Waitgroup to start / stop job, semaphore to
-	// make sure no tasks run in parallel.
-	wg        sync.WaitGroup
-	semaphore chan int
+	mutex            sync.Mutex
+	nextRun          time.Time
+	nextSlot         time.Time
+	jitter           time.Duration
+	lastRun          time.Time
+	curSchedule      Schedule
+	curInterval      time.Duration
+	running          int
+	executing        int
+	runSeq           int
+	cancelRuns       map[int]context.CancelFunc
+	overlap          OverlapPolicy
+	pending          bool
+	pendingTrigger   Trigger
+	state            State
+	store            JobStore
+	timeout          time.Duration
+	retry            *RetryPolicy
+	attempts         map[int]int
+	lastAttempts     int
+	history          *runHistory
+	listeners        []Listener
+	pool             *Pool
+	group            string
+	priority         int
+	queued           bool
+	locker           Locker
+	blackout         *Blackout
+	manualPolicy     ManualTriggerPolicy
+	lastManual       time.Time
+	debouncing       bool
+	payloads         []interface{}
+	payloadQueueSize int
+	serviceBlackout  *Blackout
+	deferring        bool
+	deferredTrigger  Trigger
+
+	// Fires deferred triggers at the end of a blackout, it
+	// is only used from within the job's go routine.
+	deferTimer Timer
+
+	// Closed once a one-shot job handled its run, see finish.
+	finished   chan struct{}
+	finishOnce sync.Once
+
+	// Waitgroup to start / stop job.
+	wg       sync.WaitGroup
+	stopOnce sync.Once
 }
 
 // NewJob creates a new job for the given task, name and duration.
 // It accepts an optional logger, if that is nil the job will be quiet.
 func NewJob(task Task, logger Logger, interval time.Duration) *Job {
+	return New(task, WithLogger(logger), WithInterval(interval))
+}
+
+// NewScheduledJob creates a new job for the given task which runs
+// according to the given schedule, e.g. one returned by ParseCron.
+// It accepts an optional logger, if that is nil the job will be quiet.
+func NewScheduledJob(task Task, logger Logger, schedule Schedule) *Job {
+	return New(task, WithLogger(logger), WithSchedule(schedule))
+}
+
+// NewJobWithClock creates a new job for the given task which runs according
+// to the given schedule and takes the time from the given clock, e.g. a
+// FakeClock. It accepts an optional logger, if that is nil the job will be
+// quiet, and an optional clock, if that is nil the system time is used.
+func NewJobWithClock(task Task, logger Logger, schedule Schedule, clock Clock) *Job {
+	return New(task, WithLogger(logger), WithSchedule(schedule), WithClock(clock))
+}
+
+// NewOneShotJob creates a new job for the given task which runs once at the
+// given time. When added to a service, the job removes itself from the service
+// after its run, it can be cancelled before by removing it from the service.
+// It accepts an optional logger, if that is nil the job will be quiet.
+func NewOneShotJob(task Task, logger Logger, at time.Time) *Job {
+	return NewScheduledJob(task, logger, At(at))
+}
+
+// NewDelayedJob creates a new job for the given task which runs once after
+// the given delay, see NewOneShotJob.
+// It accepts an optional logger, if that is nil the job will be quiet.
+func NewDelayedJob(task Task, logger Logger, delay time.Duration) *Job {
+	return NewScheduledJob(task, logger, After(delay))
+}
+
+// NewContextJob creates a new job for the given cancellable task which
+// runs according to the given schedule.
+// It accepts an optional logger, if that is nil the job will be quiet.
+func NewContextJob(task ContextTask, logger Logger, schedule Schedule) *Job {
+	return NewContext(task, WithLogger(logger), WithSchedule(schedule))
+}
+
+func newJob(task Task, ctxTask ContextTask, opts []Option) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
 	job := &Job{
 		Task:        task,
-		Logger:      logger,
-		interval:    make(chan time.Duration, 1),
+		task:        ctxTask,
+		clock:       realClock{},
+		location:    time.UTC,
+		ctx:         ctx,
+		cancelAll:   cancel,
+		schedule:    make(chan Schedule, 1),
 		stop:        make(chan struct{}),
-		runNow:      make(chan struct{}),
-		semaphore:   make(chan int, 1),
+		finished:    make(chan struct{}),
+		runNow:      make(chan Trigger),
+		runNowAsync: make(chan struct{}, 1),
+		runPayload:  make(chan struct{}, 1),
+		restore:     make(chan restoreRequest, 1),
+		rescheduled: make(chan struct{}, 1),
 		nextRun:     time.Time{},
 		lastRun:     time.Time{},
-		curInterval: interval,
-		inProgress:  false,
+		curSchedule: manualSchedule{},
+		cancelRuns:  make(map[int]context.CancelFunc),
+		attempts:    make(map[int]int),
 		state:       Enabled,
+		history:     newRunHistory(DefaultHistorySize),
+
+		runDependency:    make(chan struct{}, 1),
+		payloadQueueSize: DefaultPayloadQueueSize,
 	}
+	for _, opt := range opts {
+		opt(job)
+	}
+	job.curInterval = intervalOf(job.curSchedule)
 	job.wg.Add(1)
 	go job.start()
 	return job
 }
 
 // Stop the job.
+// A running task is cancelled and waited for; tasks
+// which don't support cancellation run until they're done.
+// Stopping a job which was stopped already has no effect.
 func (j *Job) Stop() {
-	close(j.stop)
-	j.wg.Wait()
+	done := j.shutdown()
+	j.cancelAll()
+	<-done
+}
+
+// Shutdown stops the job, it doesn't accept triggers anymore and queued runs
+// are dropped. Running tasks get until the context is done to finish,
+// afterwards they are cancelled and waited for. It returns the context's
+// error if tasks had to be cancelled.
+func (j *Job) Shutdown(ctx context.Context) error {
+	done := j.shutdown()
+	select {
+	case <-done:
+		j.cancelAll()
+		return nil
+	default:
+	}
+
+	select {
+	case <-done:
+		j.cancelAll()
+		return nil
+	case <-ctx.Done():
+	}
+	// Runs still waiting for the pool are dropped, they don't count.
+	j.mutex.Lock()
+	interrupted := j.executing > 0
+	j.mutex.Unlock()
+	if interrupted {
+		j.info("JOB=%s Deadline exceeded, cancelling task.", j.Name())
+	}
+	j.cancelAll()
+	<-done
+	if interrupted {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// shutdown stops the job's go routine without cancelling running tasks.
+// The returned channel is closed once all running tasks are finished.
+func (j *Job) shutdown() <-chan struct{} {
+	j.stopOnce.Do(func() {
+		close(j.stop)
+	})
+	done := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// Cancel cancels the currently running tasks, if any.
+// The job itself continues to run on its schedule.
+func (j *Job) Cancel() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if len(j.cancelRuns) > 0 {
+		j.info("JOB=%s Cancelling task.", j.Name())
+	}
+	for _, cancel := range j.cancelRuns {
+		cancel()
+	}
+}
+
+// SetTimeout sets the maximum duration of a single attempt, after which
+// the task's context is cancelled. A timeout of zero disables it.
+func (j *Job) SetTimeout(d time.Duration) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.timeout = d
+}
+
+// SetJitter sets the maximum random delay added to every scheduled run,
+// which spreads jobs sharing the same schedule. It applies from the next run on.
+func (j *Job) SetJitter(max time.Duration) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.jitter = max
+}
+
+// Jitter returns the maximum random delay added to every scheduled run.
+func (j *Job) Jitter() time.Duration {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.jitter
+}
+
+// Timeout returns the maximum duration of a single attempt.
+func (j *Job) Timeout() time.Duration {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.timeout
 }
 
 // Pause the job.
@@ -84,17 +327,38 @@ func (j *Job) Pause() {
 // Resume the job.
 func (j *Job) Resume() {
 	j.setState(Enabled)
+	if len(j.PendingPayloads()) > 0 {
+		j.payloadQueued()
+	}
 }
 
 // UpdateInterval updates the interval of the job.
-// This will create a new ticker and stop the old one.
+// This replaces the current schedule, cron based or not.
 func (j *Job) UpdateInterval(d time.Duration) {
-	j.interval <- d
+	j.UpdateSchedule(Every(d))
 }
 
-// RunNow triggers the job manually.
+// UpdateSchedule replaces the schedule of the job.
+// The next run is calculated from the time of the update.
+func (j *Job) UpdateSchedule(s Schedule) {
+	select {
+	case j.schedule <- s:
+	case <-j.stop:
+	}
+}
+
+// RunNow triggers the job manually, it blocks until the job picked up
+// the trigger. See TryRunNow and SetManualTriggerPolicy.
 func (j *Job) RunNow() {
-	j.runNow <- struct{}{}
+	j.trigger(ManualTrigger)
+}
+
+// trigger triggers the job unless it has been stopped.
+func (j *Job) trigger(t Trigger) {
+	select {
+	case j.runNow <- t:
+	case <-j.stop:
+	}
 }
 
 // LastRun returns when the job ran the last time.
@@ -115,7 +379,7 @@ func (j *Job) NextRun() time.Time {
 func (j *Job) InProgress() bool {
 	j.mutex.Lock()
 	defer j.mutex.Unlock()
-	return j.inProgress
+	return j.running > 0
 }
 
 // State returns whether current state
@@ -127,35 +391,59 @@ func (j *Job) State() State {
 
 func (j *Job) setState(state State) {
 	j.mutex.Lock()
-	defer j.mutex.Unlock()
 	j.state = state
+	j.mutex.Unlock()
+	j.persist()
 }
 
 // CurrentInterval returns the current interval.
+// It is zero if the job runs on a cron schedule.
 func (j *Job) CurrentInterval() time.Duration {
 	j.mutex.Lock()
 	defer j.mutex.Unlock()
 	return j.curInterval
 }
 
-// Service struct wraps a list of jobs and provides some convenience methods.
-type Service struct {
-	jobs []*Job
+// CurrentSchedule returns the current schedule.
+func (j *Job) CurrentSchedule() Schedule {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.curSchedule
 }
 
-// NewService constructs a new scheduler service.
-func NewService() *Service {
-	return &Service{}
+// Location returns the location in which the job's schedule is evaluated, see WithLocation.
+func (j *Job) Location() *time.Location {
+	return j.location
 }
 
-// AddJob adds the given job to the job list.
-func (s *Service) AddJob(j *Job) {
-	s.jobs = append(s.jobs, j)
+// OneShot returns whether the job runs only once, see NewOneShotJob.
+func (j *Job) OneShot() bool {
+	_, ok := j.CurrentSchedule().(*onceSchedule)
+	return ok
 }
 
-// Jobs returns all jobs associated to this scheduler.
-func (s *Service) Jobs() []*Job {
-	return s.jobs
+// finish marks a one-shot job as finished once its slot passed and no
+// run is in progress or pending anymore. The mutex must be held.
+func (j *Job) finish() {
+	if _, ok := j.curSchedule.(*onceSchedule); !ok {
+		return
+	}
+	if j.nextSlot.IsZero() && j.running == 0 && !j.pending {
+		j.finishOnce.Do(func() {
+			close(j.finished)
+		})
+	}
+}
+
+// intervalOf returns the interval of fixed interval schedules, zero otherwise.
+func intervalOf(s Schedule) time.Duration {
+	switch s := s.(type) {
+	case *intervalSchedule:
+		return s.interval
+	case *startSchedule:
+		return intervalOf(s.Schedule)
+	}
+	return 0
 }
 
 // State represents a jobs state.
@@ -179,90 +467,305 @@ func (s State) String() string {
 	return stateNames[s]
 }
 
-func (j *Job) run() {
+func (j *Job) run(trigger Trigger) {
 	if j.State() == Disabled {
 		j.info("JOB=%s Job is disabled.", j.Name())
+		j.emit(Event{Type: EventSkippedDisabled, Trigger: trigger})
 		return
 	}
-	select {
-	case j.semaphore <- 1:
-		j.wg.Add(1)
-		j.info("JOB=%s Starting task.", j.Name())
 
-		// Update the job meta data.
-		j.mutex.Lock()
-		j.inProgress = true
-		j.nextRun = time.Now().UTC().Add(j.curInterval)
-		j.lastRun = time.Now().UTC()
+	j.mutex.Lock()
+	if trigger == PayloadTrigger && len(j.payloads) == 0 {
 		j.mutex.Unlock()
+		return
+	}
+	if policy, in := j.blackedOut(j.now()); in && trigger != ManualTrigger && trigger != PayloadTrigger {
+		if policy == DeferBlackout && j.deferRun(trigger) {
+			j.mutex.Unlock()
+			return
+		}
+		j.mutex.Unlock()
+		j.info("JOB=%s Job is blacked out.", j.Name())
+		j.emit(Event{Type: EventSkippedBlackout, Trigger: trigger})
+		return
+	}
+	if j.running < j.overlap.limit() {
+		j.startRun(trigger)
+		j.mutex.Unlock()
+		return
+	}
 
-		go func() {
-			defer j.wg.Done()
-
-			// Run the task!
-			err := j.Run()
-			if err != nil {
-				j.error("JOB=%s Error during task execution: %v.", j.Name(), err)
-			} else {
-				j.info("JOB=%s Finished task.", j.Name())
-			}
+	// The task is still running, what happens now is up to the overlap policy.
+	switch {
+	case trigger == PayloadTrigger:
+		j.mutex.Unlock()
+		j.info("JOB=%s Task is still in progress, payload stays queued.", j.Name())
+	case j.overlap.Mode == OverlapQueue && !j.pending:
+		j.pending = true
+		j.pendingTrigger = trigger
+		j.mutex.Unlock()
+		j.info("JOB=%s Task is still in progress, queueing run.", j.Name())
+	case j.overlap.Mode == OverlapReplace:
+		j.pending = true
+		j.pendingTrigger = trigger
+		for _, cancel := range j.cancelRuns {
+			cancel()
+		}
+		j.mutex.Unlock()
+		j.info("JOB=%s Task is still in progress, replacing it.", j.Name())
+	default:
+		j.mutex.Unlock()
+		j.info("JOB=%s Task is still in progress.", j.Name())
+		j.emit(Event{Type: EventSkippedInProgress, Trigger: trigger})
+	}
+}
 
+// startRun starts a run of the task, the mutex must be held.
+func (j *Job) startRun(trigger Trigger) {
+	j.wg.Add(1)
+	j.info("JOB=%s Starting task.", j.Name())
+
+	// Update the job meta data and derive the context of this run.
+	j.running++
+	j.lastRun = j.now()
+	ctx, cancel := context.WithCancel(j.ctx)
+	var payload interface{}
+	if trigger == PayloadTrigger {
+		payload = j.nextPayload()
+		ctx = context.WithValue(ctx, payloadKey{}, payload)
+	}
+	j.runSeq++
+	id := j.runSeq
+	j.cancelRuns[id] = cancel
+
+	go func() {
+		defer j.wg.Done()
+		defer cancel()
+		j.persist()
+
+		// Wait for a free worker and run the task!
+		waited, release, err := j.waitForPool(ctx)
+		if err == errStopping {
+			j.info("JOB=%s Dropping queued task, the job is stopping.", j.Name())
 			j.mutex.Lock()
-			j.inProgress = false
-			for time.Now().UTC().After(j.nextRun) {
-				// We may need to update the next run time
-				// if this run took longer than 'interval'.
-				j.nextRun = j.nextRun.Add(j.curInterval)
-			}
+			j.running--
+			delete(j.cancelRuns, id)
 			j.mutex.Unlock()
+			return
+		}
+		start := j.now()
+		attempts := 0
+		if err == nil {
+			j.mutex.Lock()
+			j.executing++
+			j.mutex.Unlock()
+			j.emit(Event{Type: EventStarted, Time: start, Trigger: trigger})
+			attempts, err = j.execute(ctx, id)
+			release()
+			j.mutex.Lock()
+			j.executing--
+			j.mutex.Unlock()
+		}
+		end := j.now()
+
+		record := RunRecord{
+			Start:    start,
+			End:      end,
+			Duration: end.Sub(start),
+			Wait:     waited,
+			Err:      err,
+			Trigger:  trigger,
+			Payload:  payload,
+			Attempts: attempts,
+		}
+		j.mutex.Lock()
+		j.running--
+		delete(j.cancelRuns, id)
+		if attempts > 0 {
+			j.lastAttempts = attempts
+		}
+		j.history.add(record)
+
+		// Start a queued or replacing run right away, unless the job stops.
+		if j.pending && j.running < j.overlap.limit() && !j.stopping() {
+			j.pending = false
+			j.startRun(j.pendingTrigger)
+		}
+		// Continue with the queued payloads.
+		if len(j.payloads) > 0 && j.running < j.overlap.limit() && j.state == Enabled && !j.stopping() {
+			j.startRun(PayloadTrigger)
+		}
+		j.finish()
+		j.mutex.Unlock()
+
+		if err != nil {
+			j.error("JOB=%s Error during task execution: %v.", j.Name(), err)
+			j.emit(Event{Type: EventFailed, Time: end, Trigger: trigger, Record: &record, Err: err})
+		} else {
+			j.info("JOB=%s Finished task.", j.Name())
+			j.emit(Event{Type: EventSucceeded, Time: end, Trigger: trigger, Record: &record})
+		}
+	}()
+}
 
-			// Leave the semaphore.
-			<-j.semaphore
-		}()
+// stopping returns whether the job is being stopped.
+func (j *Job) stopping() bool {
+	select {
+	case <-j.stop:
+		return true
 	default:
-		j.info("JOB=%s Task is still in progress.", j.Name())
+		return false
 	}
 }
 
 func (j *Job) start() {
 	j.mutex.Lock()
-	ticker := time.NewTicker(j.curInterval)
-	j.nextRun = time.Now().UTC().Add(j.curInterval)
+	debounce := j.clock.NewTimer(time.Hour)
+	j.resetRunTimer(debounce, time.Time{})
+	j.deferTimer = j.clock.NewTimer(time.Hour)
+	j.resetRunTimer(j.deferTimer, time.Time{})
+	timer := j.clock.NewTimer(time.Hour)
+	j.scheduleSlot(timer, firstRun(j.curSchedule, j.now()))
 	j.mutex.Unlock()
 
 	j.info("JOB=%s Initialized... first run will be at %s.", j.Name(), j.NextRun().Format(time.RFC3339))
+	j.emit(Event{Type: EventScheduled, NextRun: j.NextRun()})
 	for {
 		select {
-		case <-ticker.C:
+		case <-timer.C():
 			j.info("JOB=%s Received timer trigger.", j.Name())
-			j.run()
-		case <-j.runNow:
-			j.info("JOB=%s Received manual trigger.", j.Name())
-			j.run()
-		case interval := <-j.interval:
-			j.info("JOB=%s Updating interval to %f minutes.", j.Name(), interval.Minutes())
 
-			// We have to stop the old ticker and create
-			// a new one in order to change the job interval.
-			ticker.Stop()
-			ticker = time.NewTicker(interval)
+			// Schedule the next run before running the task, the next slot
+			// follows the one that just fired. If we fell behind, e.g. because
+			// the machine was suspended, we skip the missed slots.
+			j.mutex.Lock()
+			now := j.now()
+			slot := j.nextSlot
+			next := j.curSchedule.Next(slot)
+			if !next.IsZero() && next.Before(now) {
+				next = j.curSchedule.Next(now)
+			}
+			j.scheduleSlot(timer, next)
+			j.mutex.Unlock()
+			j.emit(Event{Type: EventScheduled, NextRun: j.NextRun()})
 
+			j.runSlot(TimerTrigger, slot)
+			j.mutex.Lock()
+			j.finish()
+			j.mutex.Unlock()
+		case trigger := <-j.runNow:
+			j.info("JOB=%s Received %s trigger.", j.Name(), strings.ToLower(trigger.String()))
+			if trigger == ManualTrigger {
+				j.manualTrigger(debounce)
+			} else {
+				j.run(trigger)
+			}
+		case <-j.runNowAsync:
+			j.info("JOB=%s Received manual trigger.", j.Name())
+			j.manualTrigger(debounce)
+		case <-debounce.C():
+			j.debounced(debounce)
+		case <-j.deferTimer.C():
+			j.mutex.Lock()
+			trigger := j.deferredTrigger
+			j.deferring = false
+			j.mutex.Unlock()
+			j.info("JOB=%s Blackout is over, running deferred %s trigger.", j.Name(), strings.ToLower(trigger.String()))
+			j.run(trigger)
+		case <-j.runPayload:
+			j.run(PayloadTrigger)
+		case <-j.runDependency:
+			j.info("JOB=%s Received dependency trigger.", j.Name())
+			j.run(DependencyTrigger)
+		case schedule := <-j.schedule:
+			j.info("JOB=%s Updating schedule to %v.", j.Name(), schedule)
+
+			j.mutex.Lock()
+			j.curSchedule = schedule
+			j.curInterval = intervalOf(schedule)
+			j.scheduleSlot(timer, firstRun(schedule, j.now()))
+			j.mutex.Unlock()
+			j.persist()
+			j.emit(Event{Type: EventIntervalChanged, NextRun: j.NextRun()})
+		case req := <-j.restore:
+			j.applyRestore(req, timer)
+		case <-j.rescheduled:
 			j.mutex.Lock()
-			j.nextRun = time.Now().UTC().Add(interval)
-			j.curInterval = interval
+			j.scheduleSlot(timer, j.nextSlot)
 			j.mutex.Unlock()
+			j.emit(Event{Type: EventScheduled, NextRun: j.NextRun()})
 		case <-j.stop:
 			j.info("JOB=%s Stopping job.", j.Name())
 
-			// Stop the ticker and mark the main job go routine as done so
+			// Stop the timer and mark the main job go routine as done so
 			// that the blocking wait in the Stop() function can continue.
-			ticker.Stop()
+			timer.Stop()
+			debounce.Stop()
+			j.deferTimer.Stop()
+			j.emit(Event{Type: EventStopped})
 			j.wg.Done()
 			return
 		}
 	}
 }
 
+// now returns the current time of the job's clock in the job's location.
+func (j *Job) now() time.Time {
+	return j.clock.Now().In(j.location)
+}
+
+// reschedule makes the job's go routine schedule the current slot
+// again, e.g. after the blackout changed.
+func (j *Job) reschedule() {
+	select {
+	case j.rescheduled <- struct{}{}:
+	default:
+	}
+}
+
+// scheduleSlot sets the next slot of the job and resets the timer, a zero
+// slot disarms the timer. Slots within a skipping blackout are skipped, the
+// run of slots within a deferring blackout is moved to the blackout's end.
+// The timer fires at that time plus a random jitter, if the job has one.
+// The mutex must be held.
+func (j *Job) scheduleSlot(timer Timer, slot time.Time) {
+	if !slot.IsZero() {
+		slot = slot.In(j.location)
+	}
+	for i := 0; i < maxBlackoutSteps && !slot.IsZero(); i++ {
+		policy, in := j.blackedOut(slot)
+		next := j.curSchedule.Next(slot)
+		if !in || policy != SkipBlackout || next.IsZero() {
+			break
+		}
+		slot = next
+	}
+	j.nextSlot = slot
+	j.nextRun = slot
+	if _, in := j.blackedOut(slot); !slot.IsZero() && in {
+		if end := blackoutEnd(slot, j.blackout, j.serviceBlackout); !end.IsZero() {
+			j.nextRun = end
+		}
+	}
+	if !j.nextRun.IsZero() && j.jitter > 0 {
+		j.nextRun = j.nextRun.Add(time.Duration(rand.Int63n(int64(j.jitter))))
+	}
+	j.resetRunTimer(timer, j.nextRun)
+}
+
+// resetRunTimer resets the timer to fire at the given time.
+func (j *Job) resetRunTimer(timer Timer, at time.Time) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C():
+		default:
+		}
+	}
+	if !at.IsZero() {
+		timer.Reset(at.Sub(j.clock.Now()))
+	}
+}
+
 func (j *Job) info(msgFormat string, args ...interface{}) {
 	if j.Logger != nil {
 		j.Logger.Infof(msgFormat, args...)
@@ -1,11 +1,12 @@
 package scheduler_test
 
 import (
+	"context"
 	"sync"
 	"testing"
 	"time"
 
-	"github.com/imba3r/grabber/core/scheduler"
+	"github.com/imba3r/pkg/scheduler"
 )
 
 type testTask struct {
@@ -34,48 +35,93 @@ func (task *testTask) ID() int64 {
 	return 0
 }
 
+// waitFor polls the given condition until it is met.
+func waitFor(t *testing.T, condition func() bool) {
+	for i := 0; i < 1000; i++ {
+		if condition() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("Condition not met in time")
+}
+
+// advance advances the clock n times by the given interval, waiting for
+// the job to pick up each tick before advancing any further.
+func advance(clock *scheduler.FakeClock, n int, interval time.Duration) {
+	for i := 0; i < n; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(interval)
+	}
+	clock.BlockUntil(1)
+}
+
 func TestScheduler_Scheduling(t *testing.T) {
 	task := &testTask{}
-	job := scheduler.NewJob(task, time.Second*60)
-
-	time.Sleep(time.Millisecond * 50)
+	start := time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC)
+	clock := scheduler.NewFakeClock(start)
+	job := scheduler.NewJobWithClock(task, nil, scheduler.Every(time.Second*60), clock)
+	clock.BlockUntil(1)
 
 	nextRun := job.NextRun()
 	lastRun := job.LastRun()
 
-	if nextRun == (time.Time{}) {
+	if nextRun != start.Add(time.Second*60) {
 		t.Error("Job should have a scheduled time by now..")
 	}
 	if lastRun != (time.Time{}) {
 		t.Error("Job should not have run yet..")
 	}
 
+	clock.Advance(time.Second * 10)
 	job.UpdateInterval(time.Second * 30)
-	time.Sleep(time.Millisecond * 50)
-
-	newNextRun := job.NextRun()
+	waitFor(t, func() bool { return job.CurrentInterval() == time.Second*30 })
 
-	if nextRun == newNextRun {
+	if job.NextRun() != start.Add(time.Second*40) {
 		t.Error("A new run time should have been scheduled..")
 	}
 	job.Stop()
 }
 
+func TestScheduler_Intervals(t *testing.T) {
+	task := &testTask{}
+	clock := scheduler.NewFakeClock(time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC))
+	job := scheduler.NewJobWithClock(task, nil, scheduler.Every(time.Hour), clock)
+
+	// After three intervals the task ran three times.
+	for i := 1; i <= 3; i++ {
+		advance(clock, 1, time.Hour)
+		waitFor(t, func() bool { return !job.InProgress() && job.Stats().Runs == i })
+	}
+	job.Stop()
+	if task.getCount() != 3 {
+		t.Error("Expected 3 runs, got", task.getCount())
+	}
+}
+
 func TestScheduler_PauseResume(t *testing.T) {
 	task := &testTask{}
+	clock := scheduler.NewFakeClock(time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC))
 
 	// Start the job, interval of 10 ms, run 10 times.
-	job := scheduler.NewJob(task, time.Millisecond*10)
-	time.Sleep(time.Millisecond * 105)
+	job := scheduler.NewJobWithClock(task, nil, scheduler.Every(time.Millisecond*10), clock)
+	for i := 1; i <= 10; i++ {
+		advance(clock, 1, time.Millisecond*10)
+		waitFor(t, func() bool { return !job.InProgress() && job.Stats().Runs == i })
+	}
 
 	// Pause for 100 ms.
 	job.Pause()
-	time.Sleep(time.Millisecond * 100)
+	advance(clock, 10, time.Millisecond*10)
 
 	// Change interval to 20 ms and resume, adds another 5 runs.
 	job.UpdateInterval(time.Millisecond * 20)
+	waitFor(t, func() bool { return job.CurrentInterval() == time.Millisecond*20 })
 	job.Resume()
-	time.Sleep(time.Millisecond * 115)
+	for i := 11; i <= 15; i++ {
+		advance(clock, 1, time.Millisecond*20)
+		waitFor(t, func() bool { return !job.InProgress() && job.Stats().Runs == i })
+	}
 
 	// Job should have run 15 times now.
 	job.Stop()
@@ -114,7 +160,7 @@ func (task *longRunningTask) ID() int64 {
 func TestScheduler_ControlSpam(t *testing.T) {
 	task := &longRunningTask{}
 
-	job := scheduler.NewJob(task, time.Millisecond*10)
+	job := scheduler.NewJob(task, nil, time.Millisecond*10)
 	time.Sleep(time.Millisecond * 15)
 
 	if job.InProgress() == false {
@@ -142,7 +188,7 @@ func TestScheduler_RunNow(t *testing.T) {
 	task := &longRunningTask{}
 
 	// Start the job which will not run for one hour, hence we trigger it manually.
-	job := scheduler.NewJob(task, time.Hour*1)
+	job := scheduler.NewJob(task, nil, time.Hour*1)
 	time.Sleep(time.Millisecond * 15)
 
 	if job.InProgress() != false {
@@ -158,3 +204,125 @@ func TestScheduler_RunNow(t *testing.T) {
 
 	job.Stop()
 }
+
+type blockingTask struct {
+	countMutex sync.Mutex
+	cancelled  int
+}
+
+func (task *blockingTask) getCancelled() int {
+	task.countMutex.Lock()
+	defer task.countMutex.Unlock()
+	return task.cancelled
+}
+
+func (task *blockingTask) Run(ctx context.Context) error {
+	<-ctx.Done()
+	task.countMutex.Lock()
+	defer task.countMutex.Unlock()
+	task.cancelled = task.cancelled + 1
+	return ctx.Err()
+}
+
+func (task *blockingTask) Name() string {
+	return "blockingTask"
+}
+
+func (task *blockingTask) ID() int64 {
+	return 0
+}
+
+func TestScheduler_Cancel(t *testing.T) {
+	task := &blockingTask{}
+
+	job := scheduler.NewContextJob(task, nil, scheduler.Every(time.Hour))
+	job.RunNow()
+	time.Sleep(time.Millisecond * 15)
+
+	if job.InProgress() == false {
+		t.Error("Job should be in progress!")
+	}
+
+	job.Cancel()
+	time.Sleep(time.Millisecond * 15)
+
+	if job.InProgress() != false {
+		t.Error("Job should not be in progress anymore!")
+	}
+	if task.getCancelled() != 1 {
+		t.Error("Expected 1 cancelled run, got", task.getCancelled())
+	}
+
+	// Timeouts and stopping the job cancel the task as well.
+	job.SetTimeout(time.Millisecond * 10)
+	job.RunNow()
+	time.Sleep(time.Millisecond * 30)
+
+	job.SetTimeout(0)
+	job.RunNow()
+	job.Stop()
+
+	if task.getCancelled() != 3 {
+		t.Error("Expected 3 cancelled runs, got", task.getCancelled())
+	}
+}
+
+func TestScheduler_StartImmediately(t *testing.T) {
+	task := &testTask{}
+	start := time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC)
+	clock := scheduler.NewFakeClock(start)
+	job := scheduler.NewJobWithClock(task, nil, scheduler.StartImmediately(scheduler.Every(time.Hour)), clock)
+	defer job.Stop()
+
+	// The first run fires right away, the following ones at the interval.
+	waitFor(t, func() bool { return job.Stats().Runs == 1 })
+	waitFor(t, func() bool { return job.NextRun() == start.Add(time.Hour) })
+	advance(clock, 1, time.Hour)
+	waitFor(t, func() bool { return job.Stats().Runs == 2 })
+}
+
+func TestScheduler_StartAfter(t *testing.T) {
+	task := &testTask{}
+	start := time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC)
+	clock := scheduler.NewFakeClock(start)
+	job := scheduler.NewJobWithClock(task, nil, scheduler.StartAfter(scheduler.Every(time.Hour), time.Minute), clock)
+	defer job.Stop()
+
+	// The delay counts from the start of the job, according to its clock.
+	waitFor(t, func() bool { return job.NextRun() == start.Add(time.Minute) })
+	advance(clock, 1, time.Minute)
+	waitFor(t, func() bool { return job.Stats().Runs == 1 })
+	waitFor(t, func() bool { return job.NextRun() == start.Add(time.Hour+time.Minute) })
+
+	// The same holds for delayed one-shot jobs.
+	delayed := scheduler.New(task, scheduler.WithSchedule(scheduler.After(time.Minute)), scheduler.WithClock(clock))
+	defer delayed.Stop()
+	waitFor(t, func() bool { return delayed.NextRun() == clock.Now().Add(time.Minute) })
+	clock.Advance(time.Minute)
+	waitFor(t, func() bool { return delayed.Stats().Runs == 1 })
+	waitFor(t, func() bool { return delayed.NextRun().IsZero() })
+}
+
+func TestScheduler_Jitter(t *testing.T) {
+	task := &testTask{}
+	start := time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC)
+	clock := scheduler.NewFakeClock(start)
+	job := scheduler.NewJobWithClock(task, nil, scheduler.Every(time.Hour), clock)
+	defer job.Stop()
+	clock.BlockUntil(1)
+	job.SetJitter(time.Minute)
+
+	// Jitter delays the runs but never shifts the underlying slots.
+	advance(clock, 1, time.Hour)
+	for i := 2; i <= 4; i++ {
+		slot := start.Add(time.Hour * time.Duration(i))
+		waitFor(t, func() bool { return !job.InProgress() && job.Stats().Runs == i-1 })
+		waitFor(t, func() bool { return !job.NextRun().Before(slot) })
+		next := job.NextRun()
+		if !next.Before(slot.Add(time.Minute)) {
+			t.Errorf("Expected next run within a minute after %s, got %s", slot, next)
+		}
+		advance(clock, 1, next.Sub(clock.Now()))
+	}
+	waitFor(t, func() bool { return job.Stats().Runs == 4 })
+}
@@ -0,0 +1,184 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Service struct wraps a list of jobs and provides some convenience methods.
+type Service struct {
+	store  JobStore
+	policy MissedRunPolicy
+
+	mutex     sync.Mutex
+	jobs      []*Job
+	upstreams map[*Job][]*Job
+	satisfied map[*Job]map[*Job]bool
+	hooked    map[*Job]bool
+	pool      *Pool
+	listeners []Listener
+	blackout  *Blackout
+}
+
+// NewService constructs a new scheduler service.
+func NewService() *Service {
+	return &Service{}
+}
+
+// NewServiceWithStore constructs a new scheduler service which persists
+// the state of its jobs in the given store. Jobs added to the service
+// restore their last run, state and interval from the store; runs missed
+// in the meantime are handled according to the given policy.
+func NewServiceWithStore(store JobStore, policy MissedRunPolicy) *Service {
+	return &Service{store: store, policy: policy}
+}
+
+// AddJob adds the given job to the job list.
+func (s *Service) AddJob(j *Job) {
+	s.mutex.Lock()
+	s.jobs = append(s.jobs, j)
+	if s.pool != nil {
+		j.setPool(s.pool)
+	}
+	if s.blackout != nil {
+		j.setServiceBlackout(s.blackout)
+	}
+	for _, l := range s.listeners {
+		j.AddListener(l)
+	}
+	if j.OneShot() {
+		go s.removeFinished(j)
+	}
+	s.mutex.Unlock()
+
+	if s.store != nil {
+		j.attachStore(s.store, s.policy)
+	}
+}
+
+// removeFinished removes the given one-shot job once its run was handled,
+// which may have happened already before it was added to the service.
+func (s *Service) removeFinished(j *Job) {
+	select {
+	case <-j.finished:
+		s.RemoveJob(j)
+	case <-j.stop:
+	}
+}
+
+// RemoveJob removes the given job from the job list and stops it.
+// It returns false if the job is not part of this service.
+func (s *Service) RemoveJob(j *Job) bool {
+	s.mutex.Lock()
+	removed := false
+	for i, job := range s.jobs {
+		if job == j {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			removed = true
+			break
+		}
+	}
+	if removed {
+		s.removeDependencies(j)
+	}
+	s.mutex.Unlock()
+
+	if removed {
+		j.Stop()
+	}
+	return removed
+}
+
+// Jobs returns all jobs associated to this scheduler.
+func (s *Service) Jobs() []*Job {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]*Job(nil), s.jobs...)
+}
+
+// JobByID returns the first job whose task has the given ID.
+func (s *Service) JobByID(id int64) (*Job, bool) {
+	for _, j := range s.Jobs() {
+		if j.ID() == id {
+			return j, true
+		}
+	}
+	return nil, false
+}
+
+// JobByName returns the first job whose task has the given name.
+func (s *Service) JobByName(name string) (*Job, bool) {
+	for _, j := range s.Jobs() {
+		if j.Name() == name {
+			return j, true
+		}
+	}
+	return nil, false
+}
+
+// PauseAll pauses all jobs.
+func (s *Service) PauseAll() {
+	for _, j := range s.Jobs() {
+		j.Pause()
+	}
+}
+
+// ResumeAll resumes all jobs.
+func (s *Service) ResumeAll() {
+	for _, j := range s.Jobs() {
+		j.Resume()
+	}
+}
+
+// ShutdownReport describes how the jobs of a service were shut down.
+type ShutdownReport struct {
+	// Interrupted contains the names of the jobs whose
+	// running tasks had to be cancelled.
+	Interrupted []string
+}
+
+// Shutdown stops all jobs, they don't accept triggers anymore and queued runs
+// are dropped. Running tasks get until the context is done to finish,
+// afterwards they are cancelled and waited for. The report lists the jobs
+// which were interrupted, if there are any the context's error is returned.
+func (s *Service) Shutdown(ctx context.Context) (ShutdownReport, error) {
+	jobs := s.Jobs()
+	errs := make([]error, len(jobs))
+	var wg sync.WaitGroup
+	for i, j := range jobs {
+		wg.Add(1)
+		go func(i int, j *Job) {
+			defer wg.Done()
+			errs[i] = j.Shutdown(ctx)
+		}(i, j)
+	}
+	wg.Wait()
+
+	var report ShutdownReport
+	for i, err := range errs {
+		if err != nil {
+			report.Interrupted = append(report.Interrupted, jobs[i].Name())
+		}
+	}
+	if len(report.Interrupted) > 0 {
+		return report, ctx.Err()
+	}
+	return report, nil
+}
+
+// StopAll stops all jobs. Running tasks get until the given timeout to
+// finish, afterwards they are cancelled. The returned error lists the
+// jobs which had to be cancelled. See Shutdown.
+func (s *Service) StopAll(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	report, _ := s.Shutdown(ctx)
+	if len(report.Interrupted) > 0 {
+		return fmt.Errorf("tasks cancelled after %s: %s", timeout, strings.Join(report.Interrupted, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package scheduler_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+func TestService_Lookup(t *testing.T) {
+	s := scheduler.NewService()
+	job := scheduler.NewJob(&testTask{}, nil, time.Hour)
+	s.AddJob(job)
+
+	found, ok := s.JobByName("testTask")
+	assert.True(t, ok, "job should be found by name")
+	assert.True(t, found == job, "wrong job found by name")
+
+	found, ok = s.JobByID(0)
+	assert.True(t, ok, "job should be found by ID")
+	assert.True(t, found == job, "wrong job found by ID")
+
+	_, ok = s.JobByName("unknown")
+	assert.True(t, !ok, "unknown job should not be found")
+
+	s.PauseAll()
+	assert.Equals(t, scheduler.Disabled, job.State())
+	s.ResumeAll()
+	assert.Equals(t, scheduler.Enabled, job.State())
+
+	assert.True(t, s.RemoveJob(job), "job should be removed")
+	assert.True(t, !s.RemoveJob(job), "job should only be removed once")
+	assert.Equals(t, 0, len(s.Jobs()))
+}
+
+func TestService_StopAll(t *testing.T) {
+	s := scheduler.NewService()
+	quick := scheduler.NewJob(&longRunningTask{}, nil, time.Hour)
+	blocking := scheduler.NewContextJob(&blockingTask{}, nil, scheduler.Every(time.Hour))
+	s.AddJob(quick)
+	s.AddJob(blocking)
+
+	quick.RunNow()
+	blocking.RunNow()
+	time.Sleep(time.Millisecond * 15)
+
+	// The long running task finishes within the deadline,
+	// the blocking one has to be cancelled.
+	err := s.StopAll(time.Second * 2)
+	assert.Error(t, err)
+	assert.Equals(t, "tasks cancelled after 2s: blockingTask", err.Error())
+}
+
+func TestService_Shutdown(t *testing.T) {
+	s := scheduler.NewService()
+	idle := scheduler.NewJob(&testTask{}, nil, time.Hour)
+	blocking := scheduler.NewContextJob(&blockingTask{}, nil, scheduler.Every(time.Hour))
+	s.AddJob(idle)
+	s.AddJob(blocking)
+
+	blocking.RunNow()
+	time.Sleep(time.Millisecond * 15)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	report, err := s.Shutdown(ctx)
+	assert.Equals(t, context.DeadlineExceeded, err)
+	assert.Equals(t, []string{"blockingTask"}, report.Interrupted)
+
+	// Stopped jobs don't accept triggers anymore, stopping them again is fine.
+	assert.True(t, !blocking.TryRunNow(), "stopped job should reject triggers")
+	blocking.RunNow()
+	blocking.Stop()
+	assert.NoError(t, s.StopAll(0))
+}
+
+func TestService_OneShot(t *testing.T) {
+	start := time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC)
+	clock := scheduler.NewFakeClock(start)
+	task := &testTask{}
+
+	s := scheduler.NewService()
+	job := scheduler.NewJobWithClock(task, nil, scheduler.At(start.Add(15*time.Minute)), clock)
+	s.AddJob(job)
+	assert.True(t, job.OneShot(), "job should be a one-shot job")
+
+	// The job runs once and removes itself afterwards.
+	clock.BlockUntil(1)
+	assert.Equals(t, start.Add(15*time.Minute), job.NextRun())
+	clock.Advance(15 * time.Minute)
+	waitFor(t, func() bool { return len(s.Jobs()) == 0 })
+	assert.Equals(t, 1, task.getCount())
+	assert.True(t, job.NextRun().IsZero(), "job should not be scheduled anymore")
+
+	// Delayed jobs whose time passed fire right away.
+	s.AddJob(scheduler.NewDelayedJob(task, nil, -time.Minute))
+	waitFor(t, func() bool { return len(s.Jobs()) == 0 })
+	assert.Equals(t, 2, task.getCount())
+
+	// Removing the job before it fires cancels it.
+	job = scheduler.NewDelayedJob(task, nil, time.Hour)
+	s.AddJob(job)
+	assert.True(t, s.RemoveJob(job), "job should be removed")
+	assert.Equals(t, 2, task.getCount())
+}
+
+func TestService_OneShotFinished(t *testing.T) {
+	task := &testTask{}
+	s := scheduler.NewService()
+	defer s.StopAll(time.Second)
+
+	// Jobs which ran before they were added are removed as well.
+	job := scheduler.NewDelayedJob(task, nil, -time.Minute)
+	waitFor(t, func() bool { return task.getCount() == 1 })
+	time.Sleep(time.Millisecond * 5)
+	s.AddJob(job)
+	waitFor(t, func() bool { return len(s.Jobs()) == 0 })
+
+	// So are jobs whose slot another instance claimed.
+	dir, err := ioutil.TempDir("", "scheduler-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	locker, err := scheduler.NewFileLocker(filepath.Join(dir, "locks"))
+	assert.NoError(t, err)
+	at := time.Now().Add(-time.Minute)
+	ok, err := locker.Acquire(0, at)
+	assert.NoError(t, err)
+	assert.True(t, ok, "slot should be free")
+	s.AddJob(scheduler.New(task, scheduler.WithSchedule(scheduler.At(at)), scheduler.WithLocker(locker)))
+	waitFor(t, func() bool { return len(s.Jobs()) == 0 })
+	assert.Equals(t, 1, task.getCount())
+
+	// And jobs whose slot was skipped because the task was still running.
+	start := time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC)
+	clock := scheduler.NewFakeClock(start)
+	job = scheduler.NewContext(&blockingTask{},
+		scheduler.WithSchedule(scheduler.At(start.Add(time.Minute))), scheduler.WithClock(clock))
+	s.AddJob(job)
+	job.RunNow()
+	waitFor(t, job.InProgress)
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	time.Sleep(time.Millisecond * 5)
+	assert.Equals(t, 1, len(s.Jobs()))
+	job.Cancel()
+	waitFor(t, func() bool { return len(s.Jobs()) == 0 })
+}
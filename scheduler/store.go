@@ -0,0 +1,188 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// JobRecord contains the persisted state of a job.
+type JobRecord struct {
+	Name     string
+	LastRun  time.Time
+	NextRun  time.Time
+	State    State
+	Interval time.Duration
+}
+
+// JobStore interface describes a backend persisting job state.
+// Records are identified by the name of the job's task.
+type JobStore interface {
+	Load(name string) (JobRecord, bool, error)
+	Save(record JobRecord) error
+}
+
+// MissedRunPolicy decides what happens to runs which were
+// missed while the process was not running.
+type MissedRunPolicy int
+
+// All available missed run policies.
+const (
+	// SkipMissed ignores missed runs, the job continues with its next slot.
+	SkipMissed MissedRunPolicy = iota
+	// RunMissedOnce runs the job once right after restoring it if
+	// one or more runs were missed.
+	RunMissedOnce
+)
+
+// FileStore implements a JobStore which keeps all records in a JSON file.
+type FileStore struct {
+	path string
+
+	mutex   sync.Mutex
+	records map[string]JobRecord
+}
+
+// NewFileStore constructs a new file store, the file is created on the first save.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:    path,
+		records: make(map[string]JobRecord),
+	}
+
+	bytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read job store file: %v", err)
+	}
+
+	err = json.Unmarshal(bytes, &s.records)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal job store file: %v", err)
+	}
+	return s, nil
+}
+
+// Load returns the record of the given job, if there is one.
+func (s *FileStore) Load(name string) (JobRecord, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, ok := s.records[name]
+	return record, ok, nil
+}
+
+// Save saves the given record and writes all records to disk.
+func (s *FileStore) Save(record JobRecord) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.records[record.Name] = record
+	bytes, err := json.Marshal(s.records)
+	if err != nil {
+		return fmt.Errorf("could not marshal job store: %v", err)
+	}
+
+	// Replace the file atomically, a crash must not leave a truncated store.
+	tmp := s.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("could not create job store file: %v", err)
+	}
+	if _, err := file.Write(bytes); err != nil {
+		file.Close()
+		return fmt.Errorf("could not write job store to disk: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("could not write job store to disk: %v", err)
+	}
+	file.Close()
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("could not replace job store file: %v", err)
+	}
+	return nil
+}
+
+// restoreRequest is sent to the job's go routine to restore persisted state.
+type restoreRequest struct {
+	record JobRecord
+	policy MissedRunPolicy
+}
+
+// attachStore loads the job's state from the store and
+// makes the job persist every change from now on.
+func (j *Job) attachStore(store JobStore, policy MissedRunPolicy) {
+	j.mutex.Lock()
+	j.store = store
+	j.mutex.Unlock()
+
+	record, ok, err := store.Load(j.Name())
+	if err != nil {
+		j.error("JOB=%s Could not load job state: %v.", j.Name(), err)
+		return
+	}
+	if !ok {
+		j.persist()
+		return
+	}
+	j.restore <- restoreRequest{record, policy}
+}
+
+// applyRestore applies a restore request, it must only
+// be called from within the job's go routine.
+func (j *Job) applyRestore(req restoreRequest, timer Timer) {
+	record := req.record
+	now := j.now()
+
+	j.mutex.Lock()
+	j.lastRun = record.LastRun
+	j.state = record.State
+	// Only a changed interval replaces the schedule, which keeps
+	// e.g. aligned schedules and start wrappers intact.
+	if record.Interval > 0 && record.Interval != intervalOf(j.curSchedule) {
+		j.curSchedule = Every(record.Interval)
+		j.curInterval = record.Interval
+	}
+	missed := !record.NextRun.IsZero() && record.NextRun.Before(now)
+	if record.NextRun.IsZero() || missed {
+		j.scheduleSlot(timer, j.curSchedule.Next(now))
+	} else {
+		j.scheduleSlot(timer, record.NextRun)
+	}
+	j.mutex.Unlock()
+
+	j.info("JOB=%s Restored job state, next run will be at %s.", j.Name(), j.NextRun().Format(time.RFC3339))
+	j.persist()
+	j.emit(Event{Type: EventScheduled, NextRun: j.NextRun()})
+	if missed && req.policy == RunMissedOnce {
+		j.info("JOB=%s Catching up missed run from %s.", j.Name(), record.NextRun.Format(time.RFC3339))
+		j.runSlot(CatchUpTrigger, record.NextRun)
+	}
+}
+
+// persist saves the job's current state if the job has a store.
+func (j *Job) persist() {
+	j.mutex.Lock()
+	store := j.store
+	record := JobRecord{
+		Name:     j.Name(),
+		LastRun:  j.lastRun,
+		NextRun:  j.nextSlot,
+		State:    j.state,
+		Interval: j.curInterval,
+	}
+	j.mutex.Unlock()
+
+	if store == nil {
+		return
+	}
+	if err := store.Save(record); err != nil {
+		j.error("JOB=%s Could not persist job state: %v.", j.Name(), err)
+	}
+}
@@ -0,0 +1,106 @@
+package scheduler_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/imba3r/pkg/assert"
+	"github.com/imba3r/pkg/scheduler"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scheduler-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "jobs.json")
+	record := scheduler.JobRecord{
+		Name:     "testTask",
+		LastRun:  time.Date(2019, time.March, 15, 10, 0, 0, 0, time.UTC),
+		NextRun:  time.Date(2019, time.March, 15, 11, 0, 0, 0, time.UTC),
+		State:    scheduler.Disabled,
+		Interval: time.Hour,
+	}
+
+	s, err := scheduler.NewFileStore(path)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Save(record))
+
+	s, err = scheduler.NewFileStore(path)
+	assert.NoError(t, err)
+	loaded, ok, err := s.Load("testTask")
+	assert.NoError(t, err)
+	assert.True(t, ok, "record should exist")
+	assert.Equals(t, record, loaded)
+
+	// A write interrupted by a crash leaves the store itself intact.
+	assert.NoError(t, ioutil.WriteFile(path+".tmp", []byte(`{"testTask": {"Na`), 0644))
+	s, err = scheduler.NewFileStore(path)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Save(record))
+	_, err = os.Stat(path + ".tmp")
+	assert.True(t, os.IsNotExist(err), "temporary file should be gone")
+}
+
+func TestService_Restore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scheduler-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := scheduler.NewFileStore(filepath.Join(dir, "jobs.json"))
+	assert.NoError(t, err)
+	lastRun := time.Now().UTC().Add(-2 * time.Hour)
+	assert.NoError(t, store.Save(scheduler.JobRecord{
+		Name:     "testTask",
+		LastRun:  lastRun,
+		NextRun:  lastRun.Add(time.Hour),
+		State:    scheduler.Enabled,
+		Interval: time.Hour,
+	}))
+
+	// The job missed its last run, so it should catch up once.
+	task := &testTask{}
+	s := scheduler.NewServiceWithStore(store, scheduler.RunMissedOnce)
+	job := scheduler.NewJob(task, nil, time.Minute)
+	s.AddJob(job)
+	time.Sleep(time.Millisecond * 50)
+
+	assert.Equals(t, 1, task.getCount())
+	assert.Equals(t, time.Hour, job.CurrentInterval())
+	job.Pause()
+	job.Stop()
+
+	record, ok, err := store.Load("testTask")
+	assert.NoError(t, err)
+	assert.True(t, ok, "record should exist")
+	assert.Equals(t, scheduler.Disabled, record.State)
+	assert.True(t, record.LastRun.After(lastRun), "last run should have been updated")
+}
+
+func TestService_RestoreAligned(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scheduler-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := scheduler.NewFileStore(filepath.Join(dir, "jobs.json"))
+	assert.NoError(t, err)
+	assert.NoError(t, store.Save(scheduler.JobRecord{
+		Name:     "testTask",
+		State:    scheduler.Enabled,
+		Interval: time.Minute,
+	}))
+
+	// Restoring the same interval keeps the aligned schedule.
+	s := scheduler.NewServiceWithStore(store, scheduler.SkipMissed)
+	job := scheduler.NewScheduledJob(&testTask{}, nil, scheduler.EveryAligned(time.Minute))
+	defer job.Stop()
+	s.AddJob(job)
+	time.Sleep(time.Millisecond * 15)
+
+	assert.Equals(t, "@every 1m0s aligned", job.CurrentSchedule().(fmt.Stringer).String())
+	assert.Equals(t, time.Duration(0), job.NextRun().Sub(job.NextRun().Truncate(time.Minute)))
+}